@@ -0,0 +1,154 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Comment is a comment of JSONC data.
+type Comment struct {
+	// Kind is TriviaLineComment or TriviaBlockComment.
+	Kind TriviaKind
+
+	// Raw is the text of the comment, including the delimiters.
+	Raw string
+
+	// Text is the text of the comment without the delimiters and the
+	// surrounding white spaces. The white spaces around each line of block
+	// comments are removed too, along with the leading '*' used to decorate
+	// them, and the empty lines at the start and at the end.
+	Text string
+
+	// Range is the byte range of the comment in the data.
+	Range Range
+
+	// Line and Column are the line and the column (1-based, in runes) of
+	// the start of the comment.
+	Line, Column int
+
+	// Path is the path of the member or the element the comment is
+	// attached to, as the one used by [Modify]. It is empty for the
+	// comments before and after the root value.
+	//
+	// The comments are attached to the following member or element, unless
+	// they are on the same line of a member or an element, following it. The
+	// comments after the last member or element of an object or an array are
+	// attached to the object or the array.
+	Path []any
+}
+
+// Comments returns the comments of the JSONC data in the order they appear,
+// along with their positions and the paths they are attached to.
+//
+// It returns the errors reported by [Parse].
+func Comments(data []byte) ([]Comment, error) {
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	c := commentCollector{data: data, line: 1}
+	c.add(doc.Value.Leading, nil)
+	c.node(doc.Value, nil)
+	c.add(doc.End, nil)
+	return c.comments, nil
+}
+
+// commentCollector collects the comments of a concrete syntax tree.
+type commentCollector struct {
+	data     []byte
+	comments []Comment
+
+	// position of the last comment, to count the lines incrementally
+	off       int
+	line      int
+	lineStart int
+}
+
+// node collects the comments inside n, whose path is path.
+func (c *commentCollector) node(n *Node, path []any) {
+	for _, m := range n.Members {
+		p := appendPath(path, m.Name())
+		c.add(m.Key.Leading, p)
+		c.add(m.BeforeColon, p)
+		c.add(m.Value.Leading, p)
+		c.node(m.Value, p)
+		c.add(m.BeforeComma, p)
+		c.add(m.Trailing, p)
+	}
+	for i, e := range n.Elements {
+		p := appendPath(path, i)
+		c.add(e.Value.Leading, p)
+		c.node(e.Value, p)
+		c.add(e.BeforeComma, p)
+		c.add(e.Trailing, p)
+	}
+	c.add(n.End, path)
+}
+
+// add collects the comments in t, attached to path.
+func (c *commentCollector) add(t []Trivia, path []any) {
+	for _, tr := range t {
+		if tr.Kind == TriviaWhitespace {
+			continue
+		}
+		start := tr.Range.Start
+		if i := bytes.LastIndexByte(c.data[c.off:start], '\n'); i >= 0 {
+			c.line += bytes.Count(c.data[c.off:start], []byte{'\n'})
+			c.lineStart = c.off + i + 1
+		}
+		c.off = start
+		c.comments = append(c.comments, Comment{
+			Kind:   tr.Kind,
+			Raw:    string(tr.Raw),
+			Text:   commentText(tr),
+			Range:  tr.Range,
+			Line:   c.line,
+			Column: utf8.RuneCount(c.data[c.lineStart:start]) + 1,
+			Path:   path,
+		})
+	}
+}
+
+// appendPath returns a copy of path with key appended.
+func appendPath(path []any, key any) []any {
+	return append(path[:len(path):len(path)], key)
+}
+
+// commentText returns the text of the comment t, as described in
+// [Comment.Text].
+func commentText(t Trivia) string {
+	raw := string(t.Raw)
+	if t.Kind == TriviaLineComment {
+		return strings.TrimSpace(raw[2:])
+	}
+	lines := strings.Split(raw[2:len(raw)-2], "\n")
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "*") {
+			l = strings.TrimSpace(l[1:])
+		}
+		lines[i] = l
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
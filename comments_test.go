@@ -0,0 +1,83 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComments(t *testing.T) {
+	t.Parallel()
+	data := []byte(`// header
+{
+  // font size
+  "editor.fontSize": 14, // px
+  "list": [
+    1, /* one */
+    /**
+     * two
+     *
+     * 2
+     */
+    2
+    // after
+  ],
+  "é": /* é */ true
+  /* end */
+} // trailer`)
+	got, err := Comments(data)
+	require.NoError(t, err)
+	want := []Comment{
+		{TriviaLineComment, "// header", "header", Range{0, 9}, 1, 1, nil},
+		{TriviaLineComment, "// font size", "font size", Range{14, 26}, 3, 3, []any{"editor.fontSize"}},
+		{TriviaLineComment, "// px", "px", Range{52, 57}, 4, 26, []any{"editor.fontSize"}},
+		{TriviaBlockComment, "/* one */", "one", Range{77, 86}, 6, 8, []any{"list", 0}},
+		{TriviaBlockComment, "/**\n     * two\n     *\n     * 2\n     */", "two\n\n2", Range{91, 129}, 7, 5, []any{"list", 1}},
+		{TriviaLineComment, "// after", "after", Range{140, 148}, 13, 5, []any{"list"}},
+		{TriviaBlockComment, "/* é */", "é", Range{162, 170}, 15, 8, []any{"é"}},
+		{TriviaBlockComment, "/* end */", "end", Range{178, 187}, 16, 3, nil},
+		{TriviaLineComment, "// trailer", "trailer", Range{190, 200}, 17, 3, nil},
+	}
+	assert.Equal(t, want, got)
+	for _, c := range got {
+		assert.Equal(t, c.Raw, string(data[c.Range.Start:c.Range.End]))
+		line, col := lineColumn(data, int64(c.Range.Start))
+		assert.Equal(t, [2]int{line, col}, [2]int{c.Line, c.Column}, c.Raw)
+	}
+	t.Run("NoComments", func(t *testing.T) {
+		t.Parallel()
+		got, err := Comments([]byte(`{"a": "// not a comment"}`))
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+	t.Run("Medium", func(t *testing.T) {
+		t.Parallel()
+		got, err := Comments(_medium)
+		require.NoError(t, err)
+		assert.Equal(t, len(collectComments(t, _medium)), len(got))
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		_, err := Comments([]byte(`{"a": 1 /* }`))
+		var serr *SyntaxError
+		assert.ErrorAs(t, err, &serr)
+	})
+}
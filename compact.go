@@ -0,0 +1,118 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import "bytes"
+
+// Compact appends to dst the JSONC data with the insignificant white spaces
+// removed, as done by json.Compact.
+//
+// If keepComments is false, the comments are removed too, otherwise they
+// are preserved, so that the output is still valid JSONC on a single line:
+// line comments are converted to block comments and the new lines of block
+// comments are replaced with spaces. Any "*/" inside a line comment is
+// written as "* /" to avoid terminating the block comment early.
+//
+// It returns the errors reported by [Parse], in which case dst is not
+// modified.
+func Compact(dst *bytes.Buffer, src []byte, keepComments bool) error {
+	doc, err := Parse(src)
+	if err != nil {
+		return err
+	}
+	c := compacter{keepComments: keepComments, buf: make([]byte, 0, len(src))}
+	c.document(doc)
+	dst.Write(c.buf)
+	return nil
+}
+
+// compacter writes the compact form of a concrete syntax tree.
+type compacter struct {
+	buf          []byte
+	keepComments bool
+}
+
+// document writes doc.
+func (c *compacter) document(doc *Document) {
+	c.trivia(doc.Value.Leading)
+	c.node(doc.Value)
+	c.trivia(doc.End)
+}
+
+// node writes n, excluding its leading trivia.
+func (c *compacter) node(n *Node) {
+	switch n.Kind {
+	case KindObject:
+		c.buf = append(c.buf, '{')
+		for _, m := range n.Members {
+			c.trivia(m.Key.Leading)
+			c.buf = append(c.buf, m.Key.Raw...)
+			c.trivia(m.BeforeColon)
+			c.buf = append(c.buf, ':')
+			c.trivia(m.Value.Leading)
+			c.node(m.Value)
+			c.separator(m.BeforeComma, m.Comma, m.Trailing)
+		}
+		c.trivia(n.End)
+		c.buf = append(c.buf, '}')
+	case KindArray:
+		c.buf = append(c.buf, '[')
+		for _, e := range n.Elements {
+			c.trivia(e.Value.Leading)
+			c.node(e.Value)
+			c.separator(e.BeforeComma, e.Comma, e.Trailing)
+		}
+		c.trivia(n.End)
+		c.buf = append(c.buf, ']')
+	default:
+		c.buf = append(c.buf, n.Raw...)
+	}
+}
+
+// separator writes the trivia and the comma following a member or an
+// element.
+func (c *compacter) separator(beforeComma []Trivia, comma bool, trailing []Trivia) {
+	c.trivia(beforeComma)
+	if comma {
+		c.buf = append(c.buf, ',')
+	}
+	c.trivia(trailing)
+}
+
+// trivia writes the comments in t, if they are kept.
+func (c *compacter) trivia(t []Trivia) {
+	if !c.keepComments {
+		return
+	}
+	for _, tr := range t {
+		switch tr.Kind {
+		case TriviaLineComment:
+			text := bytes.TrimRight(tr.Raw[2:], " \t\r")
+			c.buf = append(c.buf, "/*"...)
+			c.buf = append(c.buf, bytes.ReplaceAll(text, []byte("*/"), []byte("* /"))...)
+			if len(text) > 0 && text[0] == ' ' {
+				c.buf = append(c.buf, ' ')
+			}
+			c.buf = append(c.buf, "*/"...)
+		case TriviaBlockComment:
+			for _, b := range tr.Raw {
+				if b == '\n' || b == '\r' {
+					b = ' '
+				}
+				c.buf = append(c.buf, b)
+			}
+		}
+	}
+}
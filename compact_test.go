@@ -0,0 +1,92 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name         string
+		Data         string
+		Want         string
+		WantComments string
+	}{
+		{"NoComments", "{ \"a\" : [ 1 , true ] ,\n \"b\" : \" x y \" }", `{"a":[1,true],"b":" x y "}`, `{"a":[1,true],"b":" x y "}`},
+		{"BlockComments", "/* a */ { \"a\" /* b */ : 1 /* c */ } /* d */", `{"a":1}`, `/* a */{"a"/* b */:1/* c */}/* d */`},
+		{"LineComments", "{\n  // a\n  \"a\": 1, // b\n  \"b\": 2 //c\r\n}", `{"a":1,"b":2}`, `{/* a */"a":1,/* b */"b":2/*c*/}`},
+		{"EmptyLineComment", "[1 //\n]", `[1]`, `[1/**/]`},
+		{"LineCommentWithEnd", "[1 // a */ b\n]", `[1]`, `[1/* a * / b */]`},
+		{"MultilineBlockComment", "[1 /* a\r\n b */]", `[1]`, `[1/* a   b */]`},
+		{"TrailingCommas", `[1, ]`, `[1,]`, `[1,]`},
+		{"CommentsInStrings", `{"a": "// x /* y */"}`, `{"a":"// x /* y */"}`, `{"a":"// x /* y */"}`},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			require.NoError(t, Compact(&buf, []byte(tt.Data), false))
+			assert.Equal(t, tt.Want, buf.String())
+			buf.Reset()
+			require.NoError(t, Compact(&buf, []byte(tt.Data), true))
+			assert.Equal(t, tt.WantComments, buf.String())
+			doc, err := Parse(buf.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, buf.String(), string(doc.Bytes()))
+		})
+	}
+	for _, tt := range [...]struct {
+		Name string
+		Data []byte
+	}{
+		{"Small", _small},
+		{"Medium", _medium},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			require.NoError(t, Compact(&buf, tt.Data, true))
+			assert.NotContains(t, buf.String(), "\n")
+			s, err := Sanitize(buf.Bytes())
+			require.NoError(t, err)
+			buf.Reset()
+			require.NoError(t, Compact(&buf, tt.Data, false))
+			assert.Equal(t, buf.String(), string(s))
+			var want, v map[string]any
+			require.NoError(t, Unmarshal(tt.Data, &want))
+			require.NoError(t, Unmarshal(s, &v))
+			assert.Equal(t, want, v)
+		})
+	}
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		buf.WriteString("x")
+		err := Compact(&buf, []byte(`{"a" 1}`), true)
+		var serr *SyntaxError
+		require.ErrorAs(t, err, &serr)
+		assert.Equal(t, "x", buf.String())
+	})
+}
@@ -0,0 +1,638 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+)
+
+// maxDepth is the maximum nesting depth of objects and arrays accepted by
+// [Parse], as done by the standard library.
+const maxDepth = 10000
+
+// Parse parses the JSONC data into a concrete syntax tree (CST): a lossless
+// representation of the data that preserves the comments, the white spaces
+// and the byte range of each element, so that the data can be inspected and
+// rewritten. Printing the returned document with [Document.Bytes] reproduces
+// data byte-for-byte.
+//
+// It recognizes the same comments as [Sanitize] and allows trailing commas
+// in objects and arrays, as [SanitizeJWCC]. It returns [ErrInvalidUTF8] if
+// the data is not valid UTF-8 and a [*SyntaxError] if it is not valid JSONC.
+//
+// The tree references data, which must not be modified while it is in use.
+func Parse(data []byte) (*Document, error) {
+	if !utf8.Valid(data) {
+		return nil, ErrInvalidUTF8
+	}
+	p := parser{data: data}
+	v, err := p.value(nil)
+	if err != nil {
+		return nil, err
+	}
+	end, err := p.trivia()
+	if err != nil {
+		return nil, err
+	}
+	if p.off < len(data) {
+		return nil, p.unexpected()
+	}
+	return &Document{Value: v, End: end}, nil
+}
+
+// Document is the concrete syntax tree of a JSONC document.
+type Document struct {
+	// Value is the root value. Its leading trivia are the white spaces and
+	// the comments at the beginning of the document.
+	Value *Node
+
+	// End holds the white spaces and the comments after the root value.
+	End []Trivia
+}
+
+// Bytes returns the JSONC data represented by the document, including all
+// the white spaces and the comments. If the document has not been modified,
+// it is equal to the parsed data.
+func (d *Document) Bytes() []byte {
+	dst := appendTrivia(nil, d.Value.Leading)
+	dst = appendNode(dst, d.Value)
+	return appendTrivia(dst, d.End)
+}
+
+// Kind is the kind of a [Node].
+type Kind uint8
+
+// Kinds of [Node].
+const (
+	KindObject Kind = iota + 1
+	KindArray
+	KindString
+	KindNumber
+	KindBool
+	KindNull
+)
+
+// String returns the name of the kind.
+func (k Kind) String() string {
+	switch k {
+	case KindObject:
+		return "object"
+	case KindArray:
+		return "array"
+	case KindString:
+		return "string"
+	case KindNumber:
+		return "number"
+	case KindBool:
+		return "bool"
+	case KindNull:
+		return "null"
+	}
+	return "invalid"
+}
+
+// Node is a JSON value of the concrete syntax tree.
+type Node struct {
+	// Kind is the kind of the value.
+	Kind Kind
+
+	// Leading holds the white spaces and the comments before the value.
+	Leading []Trivia
+
+	// Raw is the literal of strings (including the quotes), numbers,
+	// booleans and null. It is nil for objects and arrays.
+	Raw []byte
+
+	// Members are the members of an object.
+	Members []*Member
+
+	// Elements are the elements of an array.
+	Elements []*Element
+
+	// End holds the white spaces and the comments before the closing bracket
+	// of an object or an array that are not attached to any member or
+	// element, as the comments of an empty object.
+	End []Trivia
+
+	// Range is the byte range of the value in the parsed data, excluding the
+	// leading trivia.
+	Range Range
+}
+
+// Bytes returns the JSONC data represented by the node, excluding its
+// leading trivia.
+func (n *Node) Bytes() []byte {
+	return appendNode(nil, n)
+}
+
+// Member is a member of an object.
+type Member struct {
+	// Key is the key of the member, a [KindString] node. Its leading trivia
+	// are the white spaces and the comments before the member.
+	Key *Node
+
+	// BeforeColon holds the white spaces and the comments between the key
+	// and the colon.
+	BeforeColon []Trivia
+
+	// Value is the value of the member. Its leading trivia are the white
+	// spaces and the comments after the colon.
+	Value *Node
+
+	// BeforeComma holds the white spaces and the comments between the value
+	// and the comma.
+	BeforeComma []Trivia
+
+	// Comma reports whether the member is followed by a comma. It may be
+	// true for the last member, if the object has a trailing comma.
+	Comma bool
+
+	// Trailing holds the white spaces and the comments following the member
+	// (and its comma, if any) on the same line, as in '"a": 1, // comment'.
+	// It is empty if there are no comments.
+	Trailing []Trivia
+
+	// Range is the byte range of the member in the parsed data, from the
+	// start of the key to the end of the value.
+	Range Range
+}
+
+// Name returns the decoded key of the member.
+func (m *Member) Name() string {
+	return unquote(m.Key.Raw)
+}
+
+// Element is an element of an array.
+type Element struct {
+	// Value is the value of the element. Its leading trivia are the white
+	// spaces and the comments before the element.
+	Value *Node
+
+	// BeforeComma holds the white spaces and the comments between the value
+	// and the comma.
+	BeforeComma []Trivia
+
+	// Comma reports whether the element is followed by a comma. It may be
+	// true for the last element, if the array has a trailing comma.
+	Comma bool
+
+	// Trailing holds the white spaces and the comments following the element
+	// (and its comma, if any) on the same line. It is empty if there are no
+	// comments.
+	Trailing []Trivia
+}
+
+// TriviaKind is the kind of a [Trivia].
+type TriviaKind uint8
+
+// Kinds of [Trivia].
+const (
+	TriviaWhitespace   TriviaKind = iota + 1 // white spaces and new lines
+	TriviaLineComment                        // "// comment", without the new line
+	TriviaBlockComment                       // "/* comment */"
+)
+
+// Trivia is a run of white spaces or a comment.
+type Trivia struct {
+	// Kind is the kind of the trivia.
+	Kind TriviaKind
+
+	// Raw is the text of the trivia, including the comment delimiters.
+	Raw []byte
+
+	// Range is the byte range of the trivia in the parsed data.
+	Range Range
+}
+
+// Range is a range of bytes [Start, End) in the parsed data.
+type Range struct {
+	Start, End int
+}
+
+// parser is a recursive descent parser building the concrete syntax tree.
+type parser struct {
+	data  []byte
+	off   int
+	depth int
+}
+
+// trivia reads the white spaces and the comments at the current offset.
+func (p *parser) trivia() ([]Trivia, error) {
+	var t []Trivia
+	for {
+		start := p.off
+		kind, ok, err := p.skip()
+		if !ok || err != nil {
+			return t, err
+		}
+		t = append(t, Trivia{
+			Kind:  kind,
+			Raw:   p.data[start:p.off],
+			Range: Range{start, p.off},
+		})
+	}
+}
+
+// skipTrivia skips the white spaces and the comments at the current offset,
+// as done by trivia without collecting them.
+func (p *parser) skipTrivia() error {
+	for {
+		if _, ok, err := p.skip(); !ok || err != nil {
+			return err
+		}
+	}
+}
+
+// skip skips the white spaces or the comment at the current offset,
+// returning their kind. It reports false if there are none.
+func (p *parser) skip() (TriviaKind, bool, error) {
+	if p.off == len(p.data) {
+		return 0, false, nil
+	}
+	switch c := p.data[p.off]; {
+	case isSpace(c):
+		for p.off < len(p.data) && isSpace(p.data[p.off]) {
+			p.off++
+		}
+		return TriviaWhitespace, true, nil
+	case c == '/' && p.peek(1) == '/':
+		if i := bytes.IndexByte(p.data[p.off:], '\n'); i >= 0 {
+			p.off += i
+		} else {
+			p.off = len(p.data)
+		}
+		return TriviaLineComment, true, nil
+	case c == '/' && p.peek(1) == '*':
+		i := bytes.Index(p.data[p.off+2:], []byte("*/"))
+		if i < 0 {
+			return 0, false, p.error(p.off, reasonUnterminatedComment)
+		}
+		p.off += i + 4
+		return TriviaBlockComment, true, nil
+	}
+	return 0, false, nil
+}
+
+// value reads the value at the current offset, preceded by its trivia.
+// pending holds the trivia already read before the value.
+func (p *parser) value(pending []Trivia) (*Node, error) {
+	t, err := p.trivia()
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{Leading: append(pending, t...)}
+	if p.off == len(p.data) {
+		return nil, p.error(p.off, reasonUnexpectedEOF)
+	}
+	start := p.off
+	switch c := p.data[p.off]; {
+	case c == '{':
+		err = p.object(n)
+	case c == '[':
+		err = p.array(n)
+	case c == '"':
+		n.Kind = KindString
+		err = p.string()
+	case c == '-' || '0' <= c && c <= '9':
+		n.Kind = KindNumber
+		err = p.number()
+	case p.literal("true"), p.literal("false"):
+		n.Kind = KindBool
+	case p.literal("null"):
+		n.Kind = KindNull
+	default:
+		return nil, p.unexpected()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n.Kind != KindObject && n.Kind != KindArray {
+		n.Raw = p.data[start:p.off]
+	}
+	n.Range = Range{start, p.off}
+	return n, nil
+}
+
+// object reads the object at the current offset into n.
+func (p *parser) object(n *Node) error {
+	n.Kind = KindObject
+	if err := p.open(); err != nil {
+		return err
+	}
+	var pending []Trivia
+	for {
+		t, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		leading := append(pending, t...)
+		switch {
+		case p.off == len(p.data):
+			return p.error(p.off, reasonUnexpectedEOF)
+		case p.data[p.off] == '}':
+			n.End = leading
+			p.close()
+			return nil
+		case p.data[p.off] != '"':
+			return p.unexpected()
+		}
+		key := &Node{Kind: KindString, Leading: leading}
+		start := p.off
+		if err := p.string(); err != nil {
+			return err
+		}
+		key.Raw, key.Range = p.data[start:p.off], Range{start, p.off}
+		m := &Member{Key: key}
+		if m.BeforeColon, err = p.trivia(); err != nil {
+			return err
+		}
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		if m.Value, err = p.value(nil); err != nil {
+			return err
+		}
+		m.Range = Range{start, m.Value.Range.End}
+		n.Members = append(n.Members, m)
+		if pending, err = p.separator(&m.BeforeComma, &m.Comma, &m.Trailing, '}'); err != nil {
+			return err
+		}
+	}
+}
+
+// array reads the array at the current offset into n.
+func (p *parser) array(n *Node) error {
+	n.Kind = KindArray
+	if err := p.open(); err != nil {
+		return err
+	}
+	var pending []Trivia
+	for {
+		t, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		if p.off < len(p.data) && p.data[p.off] == ']' {
+			n.End = append(pending, t...)
+			p.close()
+			return nil
+		}
+		e := &Element{}
+		if e.Value, err = p.value(append(pending, t...)); err != nil {
+			return err
+		}
+		n.Elements = append(n.Elements, e)
+		if pending, err = p.separator(&e.BeforeComma, &e.Comma, &e.Trailing, ']'); err != nil {
+			return err
+		}
+	}
+}
+
+// open consumes the opening bracket of an object or an array.
+func (p *parser) open() error {
+	if p.depth++; p.depth > maxDepth {
+		return p.error(p.off, reasonMaxDepth)
+	}
+	p.off++
+	return nil
+}
+
+// close consumes the closing bracket of an object or an array.
+func (p *parser) close() {
+	p.depth--
+	p.off++
+}
+
+// separator reads the trivia and the comma, if any, following a member or
+// an element, up to the next member or element or the closing bracket end.
+// The trivia on the same line of the member or the element are stored in
+// trailing, the remaining ones are returned to be attached to the next
+// member, element or to the closing bracket.
+func (p *parser) separator(beforeComma *[]Trivia, comma *bool, trailing *[]Trivia, end byte) ([]Trivia, error) {
+	t, err := p.trivia()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.off == len(p.data):
+		return nil, p.error(p.off, reasonUnexpectedEOF)
+	case p.data[p.off] == ',':
+		*beforeComma, *comma = t, true
+		p.off++
+		if t, err = p.trivia(); err != nil {
+			return nil, err
+		}
+	case p.data[p.off] != end:
+		return nil, p.unexpected()
+	}
+	var rest []Trivia
+	*trailing, rest = splitTrailing(t)
+	return rest, nil
+}
+
+// splitTrailing splits t in the trivia on the same line of the preceding
+// token, if they include any comment, and the remaining ones.
+func splitTrailing(t []Trivia) (trailing, rest []Trivia) {
+	i, comment := 0, false
+	for ; i < len(t); i++ {
+		if t[i].Kind == TriviaWhitespace && bytes.IndexByte(t[i].Raw, '\n') >= 0 {
+			break
+		}
+		comment = comment || t[i].Kind != TriviaWhitespace
+	}
+	if !comment {
+		return nil, t
+	}
+	return t[:i:i], t[i:]
+}
+
+// expect consumes the byte c.
+func (p *parser) expect(c byte) error {
+	switch {
+	case p.off == len(p.data):
+		return p.error(p.off, reasonUnexpectedEOF)
+	case p.data[p.off] != c:
+		return p.unexpected()
+	}
+	p.off++
+	return nil
+}
+
+// string reads the string at the current offset.
+func (p *parser) string() error {
+	start := p.off
+	for p.off++; p.off < len(p.data); {
+		switch c := p.data[p.off]; {
+		case c == '"':
+			p.off++
+			return nil
+		case c == '\\':
+			switch p.peek(1) {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				p.off += 2
+			case 'u':
+				for i := 2; i < 6; i++ {
+					if !isHex(p.peek(i)) {
+						return p.error(p.off, reasonInvalidEscape)
+					}
+				}
+				p.off += 6
+			default:
+				return p.error(p.off, reasonInvalidEscape)
+			}
+		case c < ' ':
+			return p.error(p.off, reasonControlCharacter)
+		default:
+			p.off++
+		}
+	}
+	return p.error(start, reasonUnterminatedString)
+}
+
+// number reads the number at the current offset, as defined by the JSON
+// grammar.
+func (p *parser) number() error {
+	start := p.off
+	if p.peek(0) == '-' {
+		p.off++
+	}
+	switch c := p.peek(0); {
+	case c == '0':
+		p.off++
+	case '1' <= c && c <= '9':
+		p.digits()
+	default:
+		return p.error(start, reasonInvalidNumber)
+	}
+	if p.peek(0) == '.' {
+		p.off++
+		if p.digits() == 0 {
+			return p.error(start, reasonInvalidNumber)
+		}
+	}
+	if c := p.peek(0); c == 'e' || c == 'E' {
+		p.off++
+		if c := p.peek(0); c == '+' || c == '-' {
+			p.off++
+		}
+		if p.digits() == 0 {
+			return p.error(start, reasonInvalidNumber)
+		}
+	}
+	return nil
+}
+
+// digits consumes the decimal digits at the current offset and returns how
+// many they are.
+func (p *parser) digits() int {
+	start := p.off
+	for c := p.peek(0); '0' <= c && c <= '9'; c = p.peek(0) {
+		p.off++
+	}
+	return p.off - start
+}
+
+// literal consumes the given literal if it is at the current offset.
+func (p *parser) literal(lit string) bool {
+	if !bytes.HasPrefix(p.data[p.off:], []byte(lit)) {
+		return false
+	}
+	p.off += len(lit)
+	return true
+}
+
+// peek returns the byte at the given distance from the current offset, or 0
+// if it is out of the data.
+func (p *parser) peek(i int) byte {
+	if p.off+i < len(p.data) {
+		return p.data[p.off+i]
+	}
+	return 0
+}
+
+// unexpected returns a [*SyntaxError] for the unexpected character at the
+// current offset.
+func (p *parser) unexpected() error {
+	r, _ := utf8.DecodeRune(p.data[p.off:])
+	return p.error(p.off, fmt.Sprintf("unexpected character %q", r))
+}
+
+// error returns a [*SyntaxError] at the given offset.
+func (p *parser) error(off int, reason string) error {
+	return newSyntaxError(p.data, int64(off), reason)
+}
+
+// appendNode appends to dst the data represented by n, excluding its
+// leading trivia.
+func appendNode(dst []byte, n *Node) []byte {
+	switch n.Kind {
+	case KindObject:
+		dst = append(dst, '{')
+		for _, m := range n.Members {
+			dst = appendTrivia(dst, m.Key.Leading)
+			dst = append(dst, m.Key.Raw...)
+			dst = appendTrivia(dst, m.BeforeColon)
+			dst = append(dst, ':')
+			dst = appendTrivia(dst, m.Value.Leading)
+			dst = appendNode(dst, m.Value)
+			dst = appendSeparator(dst, m.BeforeComma, m.Comma, m.Trailing)
+		}
+		dst = appendTrivia(dst, n.End)
+		return append(dst, '}')
+	case KindArray:
+		dst = append(dst, '[')
+		for _, e := range n.Elements {
+			dst = appendTrivia(dst, e.Value.Leading)
+			dst = appendNode(dst, e.Value)
+			dst = appendSeparator(dst, e.BeforeComma, e.Comma, e.Trailing)
+		}
+		dst = appendTrivia(dst, n.End)
+		return append(dst, ']')
+	}
+	return append(dst, n.Raw...)
+}
+
+// appendSeparator appends to dst the trivia and the comma following a
+// member or an element.
+func appendSeparator(dst []byte, beforeComma []Trivia, comma bool, trailing []Trivia) []byte {
+	dst = appendTrivia(dst, beforeComma)
+	if comma {
+		dst = append(dst, ',')
+	}
+	return appendTrivia(dst, trailing)
+}
+
+// appendTrivia appends the raw text of t to dst.
+func appendTrivia(dst []byte, t []Trivia) []byte {
+	for _, tr := range t {
+		dst = append(dst, tr.Raw...)
+	}
+	return dst
+}
+
+// unquote returns the value of the JSON string literal s, which must be
+// valid.
+func unquote(s []byte) string {
+	if bytes.IndexByte(s, '\\') < 0 {
+		return string(s[1 : len(s)-1])
+	}
+	var v string
+	_ = json.Unmarshal(s, &v)
+	return v
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data []byte
+	}{
+		{"Small", _small},
+		{"SmallUncommented", _smallUncommented},
+		{"Medium", _medium},
+		{"MediumNoCommentRunes", _mediumNoCommentRunes},
+		{"Escapes", _escapes},
+		{"Scalar", []byte(" /* a */ 1 // b\n")},
+		{"TrailingCommas", []byte("{\"a\": [1, 2,], // c\n}")},
+		{"Empty", []byte("{ /* a */ } ")},
+		{"CRLF", []byte("{\r\n  \"a\": 1, // c\r\n  \"b\": 2\r\n}\r\n")},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			doc, err := Parse(tt.Data)
+			require.NoError(t, err)
+			assert.Equal(t, string(tt.Data), string(doc.Bytes()), "printed data differs")
+			checkRanges(t, tt.Data, doc.Value)
+		})
+	}
+}
+
+// checkRanges checks that the byte ranges of n and its children match the
+// printed data.
+func checkRanges(t *testing.T, data []byte, n *Node) {
+	t.Helper()
+	for _, tr := range n.Leading {
+		require.Equal(t, string(tr.Raw), string(data[tr.Range.Start:tr.Range.End]))
+	}
+	require.Equal(t, string(n.Bytes()), string(data[n.Range.Start:n.Range.End]))
+	for _, m := range n.Members {
+		checkRanges(t, data, m.Key)
+		checkRanges(t, data, m.Value)
+		require.Equal(t, m.Key.Range.Start, m.Range.Start)
+		require.Equal(t, m.Value.Range.End, m.Range.End)
+	}
+	for _, e := range n.Elements {
+		checkRanges(t, data, e.Value)
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	t.Parallel()
+	data := []byte(`// leading
+{
+	/* a */ "a": 1, // trailing a
+	"b" /* b */ : [true, null /* c */],
+	"è": "x"
+	// dangling
+}
+`)
+	doc, err := Parse(data)
+	require.NoError(t, err)
+	root := doc.Value
+	require.Equal(t, KindObject, root.Kind)
+	assert.Equal(t, "// leading", string(root.Leading[0].Raw))
+	assert.Equal(t, TriviaLineComment, root.Leading[0].Kind)
+	assert.Equal(t, []Trivia{{TriviaWhitespace, []byte("\n"), Range{len(data) - 1, len(data)}}}, doc.End)
+	require.Len(t, root.Members, 3)
+
+	a := root.Members[0]
+	assert.Equal(t, "a", a.Name())
+	assert.Equal(t, "/* a */", string(a.Key.Leading[1].Raw))
+	assert.Equal(t, TriviaBlockComment, a.Key.Leading[1].Kind)
+	assert.Equal(t, KindNumber, a.Value.Kind)
+	assert.Equal(t, "1", string(a.Value.Raw))
+	assert.True(t, a.Comma)
+	require.Len(t, a.Trailing, 2)
+	assert.Equal(t, "// trailing a", string(a.Trailing[1].Raw))
+	assert.Equal(t, `"a": 1`, string(data[a.Range.Start:a.Range.End]))
+
+	b := root.Members[1]
+	assert.Equal(t, "b", b.Name())
+	assert.Equal(t, " /* b */ ", string(appendTrivia(nil, b.BeforeColon)))
+	require.Equal(t, KindArray, b.Value.Kind)
+	require.Len(t, b.Value.Elements, 2)
+	assert.Equal(t, KindBool, b.Value.Elements[0].Value.Kind)
+	null := b.Value.Elements[1]
+	assert.Equal(t, KindNull, null.Value.Kind)
+	assert.False(t, null.Comma)
+	assert.Equal(t, " /* c */", string(appendTrivia(nil, null.Trailing)))
+	assert.Empty(t, b.Value.End)
+
+	c := root.Members[2]
+	assert.Equal(t, "è", c.Name())
+	assert.Equal(t, KindString, c.Value.Kind)
+	assert.Equal(t, `"x"`, string(c.Value.Raw))
+	assert.Empty(t, c.Trailing)
+	assert.Equal(t, "\n\t// dangling\n", string(appendTrivia(nil, root.End)))
+}
+
+func TestParseSyntaxError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want SyntaxError
+	}{
+		{"Empty", "// c\n", SyntaxError{reasonUnexpectedEOF, 5, 2, 1}},
+		{"UnterminatedComment", `{"a": 1 /* c`, SyntaxError{reasonUnterminatedComment, 8, 1, 9}},
+		{"UnterminatedString", `{"a": "b}`, SyntaxError{reasonUnterminatedString, 6, 1, 7}},
+		{"UnterminatedObject", `{"a": 1,`, SyntaxError{reasonUnexpectedEOF, 8, 1, 9}},
+		{"MissingColon", `{"a" 1}`, SyntaxError{`unexpected character '1'`, 5, 1, 6}},
+		{"MissingComma", "[1\n2]", SyntaxError{`unexpected character '2'`, 3, 2, 1}},
+		{"UnquotedKey", `{a: 1}`, SyntaxError{`unexpected character 'a'`, 1, 1, 2}},
+		{"StraySlash", `[1 / 2]`, SyntaxError{`unexpected character '/'`, 3, 1, 4}},
+		{"InvalidEscape", `["a\x"]`, SyntaxError{reasonInvalidEscape, 3, 1, 4}},
+		{"InvalidUnicodeEscape", `["\u12G4"]`, SyntaxError{reasonInvalidEscape, 2, 1, 3}},
+		{"ControlCharacter", "[\"a\tb\"]", SyntaxError{reasonControlCharacter, 3, 1, 4}},
+		{"LeadingZero", `[01]`, SyntaxError{`unexpected character '1'`, 2, 1, 3}},
+		{"InvalidNumber", `[-.5]`, SyntaxError{reasonInvalidNumber, 1, 1, 2}},
+		{"InvalidExponent", `[1e]`, SyntaxError{reasonInvalidNumber, 1, 1, 2}},
+		{"InvalidLiteral", `[tru]`, SyntaxError{`unexpected character 't'`, 1, 1, 2}},
+		{"TrailingData", `{} {}`, SyntaxError{`unexpected character '{'`, 3, 1, 4}},
+		{"MultiByte", `["é" ☃]`, SyntaxError{`unexpected character '☃'`, 6, 1, 6}},
+		{"LeadingComma", `[, 1]`, SyntaxError{`unexpected character ','`, 1, 1, 2}},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Parse([]byte(tt.Data))
+			var serr *SyntaxError
+			require.ErrorAs(t, err, &serr)
+			assert.Equal(t, tt.Want, *serr)
+		})
+	}
+	t.Run("InvalidUTF8", func(t *testing.T) {
+		t.Parallel()
+		_, err := Parse(append(_small, _invalidChar...))
+		assert.ErrorIs(t, err, ErrInvalidUTF8)
+	})
+	t.Run("MaxDepth", func(t *testing.T) {
+		t.Parallel()
+		data := bytes.Repeat([]byte("["), maxDepth+1)
+		_, err := Parse(data)
+		var serr *SyntaxError
+		require.ErrorAs(t, err, &serr)
+		assert.Equal(t, reasonMaxDepth, serr.Reason)
+	})
+}
+
+// FuzzParse checks that the parsed data is printed back byte-for-byte and
+// that it is accepted by UnmarshalJWCC.
+func FuzzParse(f *testing.F) {
+	for _, data := range [...][]byte{_small, _medium, _escapes} {
+		f.Add(data)
+	}
+	f.Add([]byte("[1, /* a */ {\"b\": [], \"c\": {} // d\n},]"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc, err := Parse(data)
+		if err != nil {
+			return
+		}
+		require.Equal(t, string(data), string(doc.Bytes()))
+		var v any
+		require.NoError(t, UnmarshalJWCC(data, &v))
+	})
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.Run("Small", func(b *testing.B) {
+		benchmarkParse(b, _small)
+	})
+	b.Run("Medium", func(b *testing.B) {
+		benchmarkParse(b, _medium)
+	})
+}
+
+func benchmarkParse(b *testing.B, data []byte) {
+	b.Helper()
+	b.ReportAllocs()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, err := Parse(data)
+			require.NoError(b, err)
+		}
+	})
+}
@@ -0,0 +1,479 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+)
+
+// A Decoder reads and decodes JSONC values from an input stream.
+//
+// It mirrors the behavior of the standard library's json.Decoder, removing
+// the comments from the input incrementally while reading it. The values are
+// unmarshaled using the library selected by the build tags (see [Unmarshal]).
+//
+// Comments are replaced with spaces before reaching the unmarshaler, so that
+// the offsets reported by [Decoder.InputOffset] match the ones of the
+// original input.
+type Decoder struct {
+	r       io.Reader
+	buf     []byte
+	scanp   int   // start of unread data in buf
+	scanned int64 // amount of data already scanned
+	line    int   // number of lines already scanned
+	col     int   // number of runes scanned in the current line
+	err     error
+
+	tokenState int
+	tokenStack []int
+}
+
+// NewDecoder returns a new decoder that reads from r.
+//
+// The decoder introduces its own buffering and may read data from r beyond
+// the JSONC values requested.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: newReader(r, true)}
+}
+
+// Decode reads the next JSONC-encoded value from its input and stores it in
+// the value pointed to by v.
+//
+// The errors reported by the unmarshaler that carry a position are wrapped
+// in a [*PositionError], as done by [Unmarshal], which reports the position
+// in the input stream. Since the comments are replaced with spaces, each of
+// their bytes is counted as a column.
+func (dec *Decoder) Decode(v any) error {
+	if dec.err != nil {
+		return dec.err
+	}
+	if err := dec.tokenPrepareForDecode(); err != nil {
+		return err
+	}
+	if !dec.tokenValueAllowed() {
+		return dec.syntaxError("not at beginning of value")
+	}
+	n, err := dec.readValue()
+	if err != nil {
+		return err
+	}
+	start := dec.scanp
+	data := dec.buf[start : start+n]
+	dec.scanp += n
+	if err = json.Unmarshal(data, v); err != nil {
+		err = dec.positionError(start, data, err)
+	}
+	dec.tokenValueEnd()
+	return err
+}
+
+// positionError returns a [*PositionError] wrapping err, reported while
+// unmarshaling data, the value at the given offset of dec.buf. If err does
+// not carry any position, it is returned as is.
+func (dec *Decoder) positionError(start int, data []byte, err error) error {
+	off, field, ok := json.ErrorOffset(data, err)
+	if !ok {
+		return err
+	}
+	off = clampOffset(data, off) + int64(start)
+	line, col := lineColumn(dec.buf, off)
+	if line == 1 {
+		col += dec.col
+	}
+	return &PositionError{
+		Err:    err,
+		Offset: dec.scanned + off,
+		Line:   dec.line + line,
+		Column: col,
+		Field:  field,
+	}
+}
+
+// Buffered returns a reader of the data remaining in the Decoder's buffer.
+// The reader is valid until the next call to [Decoder.Decode].
+//
+// Note that the comments in the buffered data are already replaced with
+// spaces.
+func (dec *Decoder) Buffered() io.Reader {
+	return bytes.NewReader(dec.buf[dec.scanp:])
+}
+
+// More reports whether there is another element in the current array or
+// object being parsed.
+func (dec *Decoder) More() bool {
+	c, err := dec.peek()
+	return err == nil && c != ']' && c != '}'
+}
+
+// InputOffset returns the input stream byte offset of the current decoder
+// position. The offset gives the location of the end of the most recently
+// returned token and the beginning of the next token.
+func (dec *Decoder) InputOffset() int64 {
+	return dec.scanned + int64(dec.scanp)
+}
+
+// readValue reads a JSON value into dec.buf. It returns the length of the
+// encoding, including the leading white spaces.
+func (dec *Decoder) readValue() (int, error) {
+	var s valueScanner
+	scanp := dec.scanp
+	var err error
+	for {
+		for ; scanp < len(dec.buf); scanp++ {
+			switch s.step(dec.buf[scanp]) {
+			case scanEnd:
+				return scanp - dec.scanp + 1, nil
+			case scanEndBefore:
+				return scanp - dec.scanp, nil
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				if s.literal {
+					return scanp - dec.scanp, nil
+				}
+				if !s.started {
+					return 0, io.EOF
+				}
+				err = io.ErrUnexpectedEOF
+			}
+			dec.err = err
+			return 0, err
+		}
+		n := scanp - dec.scanp
+		err = dec.refill()
+		scanp = dec.scanp + n
+	}
+}
+
+func (dec *Decoder) refill() error {
+	// Make room to read more into the buffer.
+	// First slide down data already consumed.
+	if dec.scanp > 0 {
+		consumed := dec.buf[:dec.scanp]
+		if nl := bytes.LastIndexByte(consumed, '\n'); nl >= 0 {
+			dec.line += bytes.Count(consumed, []byte{'\n'})
+			dec.col = utf8.RuneCount(consumed[nl+1:])
+		} else {
+			dec.col += utf8.RuneCount(consumed)
+		}
+		dec.scanned += int64(dec.scanp)
+		n := copy(dec.buf, dec.buf[dec.scanp:])
+		dec.buf = dec.buf[:n]
+		dec.scanp = 0
+	}
+
+	// Grow buffer if not large enough.
+	const minRead = 512
+	if cap(dec.buf)-len(dec.buf) < minRead {
+		newBuf := make([]byte, len(dec.buf), 2*cap(dec.buf)+minRead)
+		copy(newBuf, dec.buf)
+		dec.buf = newBuf
+	}
+
+	n, err := dec.r.Read(dec.buf[len(dec.buf):cap(dec.buf)])
+	dec.buf = dec.buf[0 : len(dec.buf)+n]
+	return err
+}
+
+// peek returns the next non-space byte of the input without consuming it.
+func (dec *Decoder) peek() (byte, error) {
+	var err error
+	for {
+		for i := dec.scanp; i < len(dec.buf); i++ {
+			c := dec.buf[i]
+			if isSpace(c) {
+				continue
+			}
+			dec.scanp = i
+			return c, nil
+		}
+		// buffer has been scanned, now report any error
+		if err != nil {
+			return 0, err
+		}
+		err = dec.refill()
+	}
+}
+
+func (dec *Decoder) syntaxError(msg string) error {
+	return fmt.Errorf("jsonc: %s (offset %d)", msg, dec.InputOffset())
+}
+
+// A Token holds a value of one of these types:
+//
+//	Delim, for the four JSON delimiters [ ] { }
+//	bool, for JSON booleans
+//	float64, for JSON numbers
+//	string, for JSON string literals
+//	nil, for JSON null
+type Token any
+
+// A Delim is a JSON array or object delimiter, one of [ ] { or }.
+type Delim rune
+
+// String returns the delimiter as a string.
+func (d Delim) String() string {
+	return string(d)
+}
+
+const (
+	tokenTopValue = iota
+	tokenArrayStart
+	tokenArrayValue
+	tokenArrayComma
+	tokenObjectStart
+	tokenObjectKey
+	tokenObjectColon
+	tokenObjectValue
+	tokenObjectComma
+)
+
+// Token returns the next JSON token in the input stream. At the end of the
+// input stream, Token returns nil, io.EOF.
+//
+// Token guarantees that the delimiters [ ] { } it returns are properly nested
+// and matched: if Token encounters an unexpected delimiter in the input, it
+// will return an error.
+//
+// The input stream consists of basic JSON values (bool, string, number, and
+// null) along with delimiters [ ] { } of type [Delim] to mark the start and
+// end of arrays and objects. Commas, colons and comments are elided.
+func (dec *Decoder) Token() (Token, error) {
+	for {
+		c, err := dec.peek()
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case '[':
+			if !dec.tokenValueAllowed() {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenStack = append(dec.tokenStack, dec.tokenState)
+			dec.tokenState = tokenArrayStart
+			return Delim('['), nil
+		case ']':
+			if dec.tokenState != tokenArrayStart && dec.tokenState != tokenArrayComma {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = dec.tokenStack[len(dec.tokenStack)-1]
+			dec.tokenStack = dec.tokenStack[:len(dec.tokenStack)-1]
+			dec.tokenValueEnd()
+			return Delim(']'), nil
+		case '{':
+			if !dec.tokenValueAllowed() {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenStack = append(dec.tokenStack, dec.tokenState)
+			dec.tokenState = tokenObjectStart
+			return Delim('{'), nil
+		case '}':
+			if dec.tokenState != tokenObjectStart && dec.tokenState != tokenObjectComma {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = dec.tokenStack[len(dec.tokenStack)-1]
+			dec.tokenStack = dec.tokenStack[:len(dec.tokenStack)-1]
+			dec.tokenValueEnd()
+			return Delim('}'), nil
+		case ':':
+			if dec.tokenState != tokenObjectColon {
+				return dec.tokenError(c)
+			}
+			dec.scanp++
+			dec.tokenState = tokenObjectValue
+			continue
+		case ',':
+			switch dec.tokenState {
+			case tokenArrayComma:
+				dec.scanp++
+				dec.tokenState = tokenArrayValue
+				continue
+			case tokenObjectComma:
+				dec.scanp++
+				dec.tokenState = tokenObjectKey
+				continue
+			}
+			return dec.tokenError(c)
+		case '"':
+			if dec.tokenState == tokenObjectStart || dec.tokenState == tokenObjectKey {
+				var x string
+				old := dec.tokenState
+				dec.tokenState = tokenTopValue
+				err := dec.Decode(&x)
+				dec.tokenState = old
+				if err != nil {
+					return nil, err
+				}
+				dec.tokenState = tokenObjectColon
+				return x, nil
+			}
+			fallthrough
+		default:
+			if !dec.tokenValueAllowed() {
+				return dec.tokenError(c)
+			}
+			var x any
+			if err := dec.Decode(&x); err != nil {
+				return nil, err
+			}
+			return x, nil
+		}
+	}
+}
+
+// tokenPrepareForDecode advances the decoder over the separator expected
+// before a value, if any.
+func (dec *Decoder) tokenPrepareForDecode() error {
+	switch dec.tokenState {
+	case tokenArrayComma:
+		c, err := dec.peek()
+		if err != nil {
+			return err
+		}
+		if c != ',' {
+			return dec.syntaxError("expected comma after array element")
+		}
+		dec.scanp++
+		dec.tokenState = tokenArrayValue
+	case tokenObjectColon:
+		c, err := dec.peek()
+		if err != nil {
+			return err
+		}
+		if c != ':' {
+			return dec.syntaxError("expected colon after object key")
+		}
+		dec.scanp++
+		dec.tokenState = tokenObjectValue
+	}
+	return nil
+}
+
+func (dec *Decoder) tokenValueAllowed() bool {
+	switch dec.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		return true
+	}
+	return false
+}
+
+func (dec *Decoder) tokenValueEnd() {
+	switch dec.tokenState {
+	case tokenArrayStart, tokenArrayValue:
+		dec.tokenState = tokenArrayComma
+	case tokenObjectValue:
+		dec.tokenState = tokenObjectComma
+	}
+}
+
+func (dec *Decoder) tokenError(c byte) (Token, error) {
+	var context string
+	switch dec.tokenState {
+	case tokenTopValue, tokenArrayStart, tokenArrayValue, tokenObjectValue:
+		context = "looking for beginning of value"
+	case tokenArrayComma:
+		context = "after array element"
+	case tokenObjectStart, tokenObjectKey:
+		context = "looking for beginning of object key string"
+	case tokenObjectColon:
+		context = "after object key"
+	case tokenObjectComma:
+		context = "after object key:value pair"
+	}
+	return nil, dec.syntaxError(fmt.Sprintf("invalid character %q %s", c, context))
+}
+
+const (
+	scanContinue  = iota // the value continues
+	scanEnd              // the value ends with the current byte
+	scanEndBefore        // the value ends before the current byte
+)
+
+// valueScanner looks for the end of the first JSON value in a sequence of
+// bytes. It only tracks strings and nesting, leaving the validation of the
+// value to the unmarshaler.
+type valueScanner struct {
+	depth    int
+	started  bool // a non-space byte has been found
+	literal  bool // scanning a top-level number, boolean or null
+	inString bool
+	escape   bool
+}
+
+// step feeds the next byte to the scanner, returning one of the scan*
+// constants.
+func (s *valueScanner) step(c byte) int {
+	switch {
+	case s.inString:
+		switch {
+		case s.escape:
+			s.escape = false
+		case c == '\\':
+			s.escape = true
+		case c == '"':
+			s.inString = false
+			if s.depth == 0 {
+				return scanEnd
+			}
+		}
+		return scanContinue
+	case s.literal:
+		switch c {
+		case ' ', '\t', '\r', '\n', ',', ':', '[', ']', '{', '}', '"':
+			return scanEndBefore
+		}
+		return scanContinue
+	case isSpace(c):
+		return scanContinue
+	}
+	s.started = true
+	switch c {
+	case '"':
+		s.inString = true
+	case '{', '[':
+		s.depth++
+	case '}', ']':
+		if s.depth > 0 {
+			s.depth--
+		}
+		if s.depth == 0 {
+			return scanEnd
+		}
+	case ',', ':':
+		if s.depth == 0 {
+			// not a value: let the unmarshaler report the error
+			return scanEnd
+		}
+	default:
+		if s.depth == 0 {
+			s.literal = true
+		}
+	}
+	return scanContinue
+}
+
+func isSpace(c byte) bool {
+	return c <= ' ' && (c == ' ' || c == '\t' || c == '\r' || c == '\n')
+}
@@ -0,0 +1,182 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecoder(t *testing.T) {
+	t.Parallel()
+	t.Run("Small", func(t *testing.T) {
+		t.Parallel()
+		decoderTest(t, _small, Small{})
+	})
+	t.Run("Medium", func(t *testing.T) {
+		t.Parallel()
+		decoderTest(t, _medium, Medium{})
+	})
+	t.Run("Stream", func(t *testing.T) {
+		t.Parallel()
+		dec := NewDecoder(strings.NewReader(`
+			// first
+			{"a": 1} /* second */ {"a": 2}
+			[3, /* ] */ "4 // not a comment"] // trailing
+			5`))
+		var v any
+		require.NoError(t, dec.Decode(&v))
+		assert.Equal(t, map[string]any{"a": float64(1)}, v)
+		require.NoError(t, dec.Decode(&v))
+		assert.Equal(t, map[string]any{"a": float64(2)}, v)
+		v = nil
+		require.NoError(t, dec.Decode(&v))
+		assert.Equal(t, []any{float64(3), "4 // not a comment"}, v)
+		require.NoError(t, dec.Decode(&v))
+		assert.Equal(t, float64(5), v)
+		assert.ErrorIs(t, dec.Decode(&v), io.EOF)
+	})
+	t.Run("UnexpectedEOF", func(t *testing.T) {
+		t.Parallel()
+		var v any
+		err := NewDecoder(strings.NewReader(`{"a": /* 1 */`)).Decode(&v)
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+	t.Run("InputOffset", func(t *testing.T) {
+		t.Parallel()
+		data := `/* x */ {"a": 1} // y
+		[2]`
+		dec := NewDecoder(strings.NewReader(data))
+		var v any
+		require.NoError(t, dec.Decode(&v))
+		assert.Equal(t, int64(strings.Index(data, "}")+1), dec.InputOffset())
+		require.NoError(t, dec.Decode(&v))
+		assert.Equal(t, int64(len(data)), dec.InputOffset())
+	})
+	t.Run("PositionError", func(t *testing.T) {
+		t.Parallel()
+		for _, value := range [...]string{`{"a": "x"}`, `{"a": 1 x}`} {
+			var want *PositionError
+			require.ErrorAs(t, Unmarshal([]byte(value), &struct{ A int }{}), &want)
+			data := "{\"a\": 1} /* é */\n// c\n  " + value
+			for _, r := range [...]io.Reader{strings.NewReader(data), iotest.OneByteReader(strings.NewReader(data))} {
+				dec := NewDecoder(r)
+				var v struct{ A int }
+				require.NoError(t, dec.Decode(&v))
+				var perr *PositionError
+				require.ErrorAs(t, dec.Decode(&v), &perr)
+				assert.Equal(t, int64(strings.Index(data, value))+want.Offset, perr.Offset, value)
+				assert.Equal(t, 3, perr.Line, value)
+				assert.Equal(t, want.Column+2, perr.Column, value)
+				assert.Equal(t, want.Field, perr.Field, value)
+			}
+		}
+	})
+	t.Run("Buffered", func(t *testing.T) {
+		t.Parallel()
+		dec := NewDecoder(strings.NewReader(`{"a": 1} /* x */ 2`))
+		var v any
+		require.NoError(t, dec.Decode(&v))
+		b, err := io.ReadAll(dec.Buffered())
+		require.NoError(t, err)
+		assert.Equal(t, "         2", string(b))
+	})
+}
+
+func decoderTest[T DataType](t *testing.T, data []byte, dt T) {
+	t.Helper()
+	for _, tt := range []struct {
+		Name   string
+		Reader func(r io.Reader) io.Reader
+	}{
+		{"Full", func(r io.Reader) io.Reader { return r }},
+		{"OneByte", iotest.OneByteReader},
+		{"Half", iotest.HalfReader},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			j := dt
+			dec := NewDecoder(tt.Reader(bytes.NewReader(data)))
+			require.NoError(t, dec.Decode(&j), "decode failed")
+			FieldsValue(t, j)
+			assert.ErrorIs(t, dec.Decode(&j), io.EOF)
+		})
+	}
+}
+
+func TestDecoderToken(t *testing.T) {
+	t.Parallel()
+	dec := NewDecoder(strings.NewReader(`{
+		// comment
+		"a": [1, true, null, "/* x */"], /* comment */
+		"b": {"c": "d"}
+	}`))
+	var tokens []Token
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		tokens = append(tokens, tok)
+	}
+	assert.Equal(t, []Token{
+		Delim('{'),
+		"a", Delim('['), float64(1), true, nil, "/* x */", Delim(']'),
+		"b", Delim('{'), "c", "d", Delim('}'),
+		Delim('}'),
+	}, tokens)
+
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		dec := NewDecoder(strings.NewReader(`{"a" 1}`))
+		_, err := dec.Token()
+		require.NoError(t, err)
+		_, err = dec.Token()
+		require.NoError(t, err)
+		_, err = dec.Token()
+		assert.Error(t, err)
+	})
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	b.Run("Small", func(b *testing.B) {
+		benchmarkDecoder(b, _small, Small{})
+	})
+	b.Run("Medium", func(b *testing.B) {
+		benchmarkDecoder(b, _medium, Medium{})
+	})
+}
+
+func benchmarkDecoder[T DataType](b *testing.B, data []byte, dt T) {
+	b.Helper()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			j := dt
+			require.NoError(b, NewDecoder(bytes.NewReader(data)).Decode(&j))
+			FieldsValue(b, j)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"io"
+	"strings"
+)
+
+// An Encoder writes JSONC values and comments to an output stream.
+//
+// It mirrors the behavior of the standard library's json.Encoder, adding
+// the comments of the tagged struct fields as done by [Marshal] and
+// [MarshalIndent], and allows to write free-standing comments between the
+// values with [Encoder.WriteComment].
+type Encoder struct {
+	w          io.Writer
+	err        error
+	prefix     string
+	indent     string
+	escapeHTML bool
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, escapeHTML: true}
+}
+
+// Encode writes the JSONC encoding of v to the stream, followed by a new
+// line.
+//
+// The comments of the tagged struct fields are written as done by
+// [MarshalIndent] if an indentation is set with [Encoder.SetIndent], or as
+// done by [Marshal] otherwise.
+func (enc *Encoder) Encode(v any) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	c := commenter{prefix: enc.prefix, indent: enc.indent}
+	c.indented = enc.prefix != "" || enc.indent != ""
+	doc, err := marshalDocument(v, c, enc.escapeHTML)
+	if err != nil {
+		return err
+	}
+	var b []byte
+	if c.indented {
+		f := formatter{prefix: enc.prefix, indent: enc.indent}
+		f.document(doc)
+		b = f.buf
+	} else {
+		c := compacter{keepComments: true}
+		c.document(doc)
+		b = c.buf
+	}
+	return enc.write(append(b, '\n'))
+}
+
+// WriteComment writes text to the stream as line comments, one for each
+// line of text, followed by a new line. It can be used to write headers or
+// to separate the values written by [Encoder.Encode].
+func (enc *Encoder) WriteComment(text string) error {
+	if enc.err != nil {
+		return enc.err
+	}
+	var b strings.Builder
+	for _, l := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		b.WriteString("//")
+		if l != "" {
+			b.WriteString(" " + l)
+		}
+		b.WriteByte('\n')
+	}
+	return enc.write([]byte(b.String()))
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded value
+// as if indented by [Indent]. Calling SetIndent("", "") disables
+// indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix, enc.indent = prefix, indent
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings, as done by json.Encoder. The default
+// behavior is to escape &, <, and > to \u0026, \u003c, and \u003e.
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.escapeHTML = on
+}
+
+// write writes b to the stream, keeping the first error.
+func (enc *Encoder) write(b []byte) error {
+	if _, err := enc.w.Write(b); err != nil {
+		enc.err = err
+	}
+	return enc.err
+}
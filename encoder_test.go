@@ -0,0 +1,90 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncoder(t *testing.T) {
+	t.Parallel()
+	t.Run("Compact", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		require.NoError(t, enc.WriteComment("Generated by test\n\nDo not edit"))
+		require.NoError(t, enc.Encode(marshalRoute{Path: "<a&b>"}))
+		require.NoError(t, enc.WriteComment("next"))
+		require.NoError(t, enc.Encode([]int{1, 2}))
+		assert.Equal(t, "// Generated by test\n//\n// Do not edit\n"+
+			`{/* Route path */"path":"\u003ca\u0026b\u003e"}`+"\n// next\n[1,2]\n", buf.String())
+		dec := NewDecoder(&buf)
+		var r marshalRoute
+		require.NoError(t, dec.Decode(&r))
+		assert.Equal(t, "<a&b>", r.Path)
+		var a []int
+		require.NoError(t, dec.Decode(&a))
+		assert.Equal(t, []int{1, 2}, a)
+		assert.ErrorIs(t, dec.Decode(&a), io.EOF)
+	})
+	t.Run("Indent", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		require.NoError(t, enc.WriteComment("header"))
+		require.NoError(t, enc.Encode(marshalRoute{Path: "<a&b>"}))
+		assert.Equal(t, "// header\n{\n  // Route path\n  \"path\": \"<a&b>\"\n}\n", buf.String())
+	})
+	t.Run("Compatibility", func(t *testing.T) {
+		t.Parallel()
+		var m Medium
+		require.NoError(t, Unmarshal(_medium, &m))
+		want, err := json.MarshalIndent(m, "> ", "\t")
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		enc.SetIndent("> ", "\t")
+		require.NoError(t, enc.Encode(m))
+		assert.Equal(t, string(want)+"\n", buf.String())
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		enc := NewEncoder(&buf)
+		assert.Error(t, enc.Encode(func() {}))
+		require.NoError(t, enc.Encode(1), "marshal errors are not sticky")
+		werr := errors.New("write error")
+		enc = NewEncoder(errorWriter{werr})
+		assert.ErrorIs(t, enc.WriteComment("a"), werr)
+		assert.ErrorIs(t, enc.Encode(1), werr)
+	})
+}
+
+// errorWriter is an io.Writer always returning err.
+type errorWriter struct{ err error }
+
+func (w errorWriter) Write([]byte) (int, error) { return 0, w.err }
@@ -0,0 +1,203 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+)
+
+// ErrInvalidUTF8 is returned by Sanitize if the data is not valid UTF-8.
+var ErrInvalidUTF8 = errors.New("jsonc: invalid UTF-8")
+
+// Reasons reported by [SyntaxError].
+const (
+	reasonUnterminatedComment = "unterminated comment block"
+	reasonUnterminatedString  = "unterminated string"
+	reasonStrayCommentEnd     = `unexpected comment block terminator "*/"`
+	reasonStraySlash          = `unexpected "/" outside of strings and comments`
+	reasonStrayStar           = `unexpected "*" outside of strings and comments`
+	reasonUnexpectedEOF       = "unexpected end of data"
+	reasonInvalidEscape       = "invalid escape sequence in string"
+	reasonControlCharacter    = "invalid control character in string"
+	reasonInvalidNumber       = "invalid number"
+	reasonMaxDepth            = "exceeded max depth"
+)
+
+// SyntaxError describes malformed JSONC data, as a comment block or a string
+// that is not terminated. It reports the position of the construct that
+// caused the error.
+type SyntaxError struct {
+	// Reason is the description of the error.
+	Reason string
+
+	// Offset is the byte offset in the data at which the error occurred.
+	Offset int64
+
+	// Line is the line number (1-based) at which the error occurred.
+	Line int
+
+	// Column is the column number (1-based), in runes, at which the error
+	// occurred.
+	Column int
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("jsonc: %s at line %d, column %d", e.Reason, e.Line, e.Column)
+}
+
+// PositionError wraps an error reported by the JSON library while
+// unmarshaling the sanitized data, translating its position to the original
+// JSONC data.
+type PositionError struct {
+	// Err is the error reported by the JSON library.
+	Err error
+
+	// Offset is the byte offset in the original data at which the error
+	// occurred.
+	Offset int64
+
+	// Line is the line number (1-based) at which the error occurred.
+	Line int
+
+	// Column is the column number (1-based), in runes, at which the error
+	// occurred.
+	Column int
+
+	// Field is the path of the field related to the error, as reported by
+	// the JSON library. It may be empty.
+	Field string
+}
+
+// Error implements the error interface.
+func (e *PositionError) Error() string {
+	return fmt.Sprintf("jsonc: %v at line %d, column %d", e.Err, e.Line, e.Column)
+}
+
+// Unwrap returns the error reported by the JSON library.
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// newPositionError returns a [*PositionError] wrapping err, reported while
+// unmarshaling data, the sanitized version of src. original maps the offsets
+// of data to the ones of src. If err does not carry any position, it is
+// returned as is.
+func newPositionError(src, data []byte, original func(int64) int64, err error) error {
+	off, field, ok := json.ErrorOffset(data, err)
+	if !ok {
+		return err
+	}
+	off = clampOffset(src, original(off))
+	line, col := lineColumn(src, off)
+	return &PositionError{
+		Err:    err,
+		Offset: off,
+		Line:   line,
+		Column: col,
+		Field:  field,
+	}
+}
+
+// newSyntaxError returns a [*SyntaxError] for the byte at the given offset
+// of data.
+func newSyntaxError(data []byte, off int64, reason string) *SyntaxError {
+	off = clampOffset(data, off)
+	line, col := lineColumn(data, off)
+	return &SyntaxError{
+		Reason: reason,
+		Offset: off,
+		Line:   line,
+		Column: col,
+	}
+}
+
+// clampOffset returns off limited to the bounds of data.
+func clampOffset(data []byte, off int64) int64 {
+	switch {
+	case off < 0:
+		return 0
+	case off > int64(len(data)):
+		return int64(len(data))
+	}
+	return off
+}
+
+// lineColumn returns the line and the column (1-based, in runes) of the byte
+// at the given offset of data.
+func lineColumn(data []byte, off int64) (line, col int) {
+	data = data[:off]
+	nl := bytes.LastIndexByte(data, '\n')
+	return bytes.Count(data, []byte{'\n'}) + 1, utf8.RuneCount(data[nl+1:]) + 1
+}
+
+// offsetMap maps the offsets of sanitized data to the ones of the original
+// data. Each entry reports the number of bytes removed from the original
+// data before the given offset of the sanitized data.
+type offsetMap []offsetShift
+
+type offsetShift struct {
+	off     int64 // offset in the sanitized data
+	removed int64 // bytes removed before off
+}
+
+// set records that the bytes from the given offset of the sanitized data are
+// shifted by the given amount in the original data. removed is negative if
+// bytes have been added.
+func (m *offsetMap) set(off, removed int64) {
+	if l := len(*m); l > 0 {
+		last := &(*m)[l-1]
+		switch {
+		case last.removed == removed:
+			return
+		case last.off == off:
+			last.removed = removed
+			return
+		}
+	} else if removed == 0 {
+		return
+	}
+	*m = append(*m, offsetShift{off, removed})
+}
+
+// remove records that n bytes have been removed at the given offset of the
+// sanitized data.
+func (m *offsetMap) remove(off, n int64) {
+	if l := len(*m); l > 0 {
+		last := &(*m)[l-1]
+		if last.off == off {
+			last.removed += n
+			return
+		}
+		n += last.removed
+	}
+	*m = append(*m, offsetShift{off, n})
+}
+
+// original returns the offset in the original data corresponding to the
+// given offset of the sanitized data.
+func (m offsetMap) original(off int64) int64 {
+	i := sort.Search(len(m), func(i int) bool { return m[i].off > off })
+	if i == 0 {
+		return off
+	}
+	return off + m[i-1].removed
+}
@@ -16,6 +16,9 @@
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/marcozac/go-jsonc"
 )
@@ -48,3 +51,66 @@ func ExampleUnmarshal_sanitizeError() {
 	// Output:
 	// jsonc: invalid UTF-8
 }
+
+func ExampleDecoder() {
+	r := strings.NewReader(`
+		// first value
+		{"foo": "bar"}
+		/* second value */
+		{"foo": "baz"}
+	`)
+
+	dec := jsonc.NewDecoder(r)
+	for {
+		var v struct{ Foo string }
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+		fmt.Println(v.Foo)
+	}
+
+	// Output:
+	// bar
+	// baz
+}
+
+func ExampleNewReader() {
+	r := jsonc.NewReader(strings.NewReader(`{"foo": /* a comment */ "bar"}`))
+
+	if _, err := io.Copy(os.Stdout, r); err != nil {
+		panic(err)
+	}
+
+	// Output:
+	// {"foo":  "bar"}
+}
+
+func ExampleSyntaxError() {
+	var v interface{}
+
+	data := []byte(`{
+	"foo": "bar" /* unterminated comment
+}`)
+
+	err := jsonc.Unmarshal(data, &v)
+	fmt.Println(err)
+
+	// Output:
+	// jsonc: unterminated comment block at line 2, column 15
+}
+
+func ExampleSanitizeStrict() {
+	data := []byte(`{"a": 1 / 2}`)
+
+	s, err := jsonc.Sanitize(data)
+	fmt.Println(string(s), err)
+
+	_, err = jsonc.SanitizeStrict(data)
+	fmt.Println(err)
+
+	// Output:
+	// {"a": 1  2} <nil>
+	// jsonc: unexpected "/" outside of strings and comments at line 1, column 9
+}
@@ -0,0 +1,266 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Format returns the JSONC data indented with two spaces per level,
+// preserving the comments, as done by [Indent], and terminated by a new
+// line.
+//
+// Formatting the output again returns the same data, so that Format can be
+// used to check whether JSONC files are formatted.
+func Format(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// Indent appends to dst an indented form of the JSONC data, as done by
+// json.Indent, preserving the comments.
+//
+// Each member and element is placed on its own line, beginning with prefix
+// followed by one or more copies of indent according to the nesting. Empty
+// objects and arrays are written as {} and [], unless they contain
+// comments.
+//
+// The comments are kept attached to the members and elements they refer to:
+// the comments on their own lines are placed on their own lines before the
+// following member or element, while the ones on the same line of a member
+// or element stay on the same line. Blank lines between members, elements
+// and comments are preserved, collapsing multiple ones into one, so that
+// the grouping of the members is not lost. Line comments always end the
+// line, even if it requires moving the following token to a new line.
+//
+// Trailing commas are preserved, while the white spaces around the data are
+// removed. New lines are written as "\n".
+//
+// It returns the errors reported by [Parse], in which case dst is not
+// modified.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	doc, err := Parse(src)
+	if err != nil {
+		return err
+	}
+	f := formatter{prefix: prefix, indent: indent}
+	f.document(doc)
+	dst.Write(f.buf)
+	return nil
+}
+
+// formatter writes the indented form of a concrete syntax tree.
+type formatter struct {
+	buf    []byte
+	prefix string
+	indent string
+	depth  int
+
+	// needNL is true after a line comment, which must be followed by a new
+	// line.
+	needNL bool
+}
+
+// document writes doc.
+func (f *formatter) document(doc *Document) {
+	nl := -1 // no comments written yet
+	for _, t := range doc.Value.Leading {
+		nl = f.comment(t, nl)
+	}
+	if nl >= 0 {
+		f.separate(nl)
+	}
+	f.value(doc.Value)
+	nl = 0
+	for _, t := range doc.End {
+		nl = f.comment(t, nl)
+	}
+}
+
+// comment writes the comment t on the same line of the previous token, if
+// nl, the number of new lines since the previous token, is 0, or on a new
+// line. If t is a white space, it only returns the updated count of new
+// lines.
+func (f *formatter) comment(t Trivia, nl int) int {
+	if t.Kind == TriviaWhitespace {
+		if nl < 0 {
+			return nl
+		}
+		return nl + bytes.Count(t.Raw, []byte("\n"))
+	}
+	if nl >= 0 {
+		f.separate(nl)
+	}
+	f.write(t)
+	return 0
+}
+
+// separate writes a space or, if nl is greater than 0, a new line, adding a
+// blank line if nl is greater than 1.
+func (f *formatter) separate(nl int) {
+	if nl > 0 || f.needNL {
+		f.newline(nl > 1)
+		return
+	}
+	f.buf = append(f.buf, ' ')
+}
+
+// newline writes a new line, preceded by a blank line if blank is true,
+// and the indentation of the current depth.
+func (f *formatter) newline(blank bool) {
+	if blank {
+		f.buf = append(f.buf, '\n')
+	}
+	f.buf = append(f.buf, '\n')
+	f.buf = append(f.buf, f.prefix...)
+	f.buf = append(f.buf, strings.Repeat(f.indent, f.depth)...)
+	f.needNL = false
+}
+
+// token writes b, moving it to a new line if it follows a line comment.
+func (f *formatter) token(b ...byte) {
+	if f.needNL {
+		f.newline(false)
+	}
+	f.buf = append(f.buf, b...)
+}
+
+// write writes the comment t.
+func (f *formatter) write(t Trivia) {
+	raw := t.Raw
+	if t.Kind == TriviaLineComment {
+		raw = bytes.TrimRight(raw, " \t\r")
+	}
+	f.token(raw...)
+	f.needNL = t.Kind == TriviaLineComment
+}
+
+// inline writes the comments in t on the current line.
+func (f *formatter) inline(t []Trivia) {
+	for _, tr := range t {
+		if tr.Kind != TriviaWhitespace {
+			f.separate(0)
+			f.write(tr)
+		}
+	}
+}
+
+// value writes n, excluding its leading trivia.
+func (f *formatter) value(n *Node) {
+	switch n.Kind {
+	case KindObject:
+		f.container('{', '}', len(n.Members), func(i int) {
+			m := n.Members[i]
+			f.leading(m.Key.Leading, i == 0)
+			f.token(m.Key.Raw...)
+			f.inline(m.BeforeColon)
+			f.token(':')
+			f.inline(m.Value.Leading)
+			f.separate(0)
+			f.value(m.Value)
+			f.separator(m.BeforeComma, m.Comma || i < len(n.Members)-1, m.Trailing)
+		}, n.End)
+	case KindArray:
+		f.container('[', ']', len(n.Elements), func(i int) {
+			e := n.Elements[i]
+			f.leading(e.Value.Leading, i == 0)
+			f.value(e.Value)
+			f.separator(e.BeforeComma, e.Comma || i < len(n.Elements)-1, e.Trailing)
+		}, n.End)
+	default:
+		f.token(n.Raw...)
+	}
+}
+
+// container writes an object or an array with n members or elements,
+// written by item, and the given dangling trivia.
+func (f *formatter) container(open, close byte, n int, item func(i int), end []Trivia) {
+	f.token(open)
+	if n == 0 && !triviaComments(end) {
+		f.buf = append(f.buf, close)
+		return
+	}
+	f.depth++
+	for i := 0; i < n; i++ {
+		item(i)
+	}
+	f.comments(end, n == 0)
+	f.depth--
+	f.newline(false)
+	f.buf = append(f.buf, close)
+}
+
+// leading writes the leading trivia of a member or an element and moves to
+// its line. If first is true, it is the first one of the container.
+func (f *formatter) leading(t []Trivia, first bool) {
+	started, nl := f.comments(t, first)
+	switch {
+	case !started:
+		f.newline(nl > 1 && !first)
+	default:
+		f.separate(nl)
+	}
+}
+
+// comments writes the comments in t, which follow the opening bracket of a
+// container if first is true, or a member or an element otherwise. Each
+// comment on its own line is written on a new line, while a comment on the
+// same line of the opening bracket stays on it.
+//
+// It reports whether any comment was written on a new line, and returns
+// the number of new lines after the last comment.
+func (f *formatter) comments(t []Trivia, first bool) (started bool, nl int) {
+	for i, tr := range t {
+		switch {
+		case tr.Kind == TriviaWhitespace:
+			nl += bytes.Count(tr.Raw, []byte("\n"))
+			continue
+		case !started && first && nl == 0 && endsLine(t, i):
+			f.separate(0)
+		case !started:
+			f.newline(nl > 1 && !first)
+			started = true
+		default:
+			f.separate(nl)
+		}
+		f.write(tr)
+		nl = 0
+	}
+	return started, nl
+}
+
+// separator writes the trivia and the comma following a member or an
+// element. The comma is written before the comments, so that it is not
+// commented out by a line comment.
+func (f *formatter) separator(beforeComma []Trivia, comma bool, trailing []Trivia) {
+	if comma {
+		f.token(',')
+	}
+	f.inline(beforeComma)
+	f.inline(trailing)
+}
+
+// endsLine reports whether the comment t[i] is followed by a new line.
+func endsLine(t []Trivia, i int) bool {
+	if t[i].Kind == TriviaLineComment {
+		return true
+	}
+	return i+1 < len(t) && bytes.IndexByte(t[i+1].Raw, '\n') >= 0
+}
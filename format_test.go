@@ -0,0 +1,157 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{"Scalar", " 1 ", "1\n"},
+		{"Compact", `{"a":1,"b":[true,null,"x"]}`, "{\n  \"a\": 1,\n  \"b\": [\n    true,\n    null,\n    \"x\"\n  ]\n}\n"},
+		{"Empty", `{"a": { }, "b": [ ]}`, "{\n  \"a\": {},\n  \"b\": []\n}\n"},
+		{"EmptyWithComment", `{"a": {/* c */}}`, "{\n  \"a\": {\n    /* c */\n  }\n}\n"},
+		{"LeadingComments", "{\n// a\n/* b */\n\"a\": 1}", "{\n  // a\n  /* b */\n  \"a\": 1\n}\n"},
+		{"InlineComment", "{/* a */ \"a\": 1}", "{\n  /* a */ \"a\": 1\n}\n"},
+		{"OpeningBracketComment", "{ // a\n\"a\": 1}", "{ // a\n  \"a\": 1\n}\n"},
+		{"TrailingComments", "{\"a\": 1, // a\n\"b\": 2 /* b */\n}", "{\n  \"a\": 1, // a\n  \"b\": 2 /* b */\n}\n"},
+		{"CommentBeforeComma", "[1 // a\n, 2]", "[\n  1, // a\n  2\n]\n"},
+		{"CommentsAroundColon", "{\"a\" /* b */ : /* c */ 1}", "{\n  \"a\" /* b */: /* c */ 1\n}\n"},
+		{"LineCommentBeforeColon", "{\"a\" // b\n: 1}", "{\n  \"a\" // b\n  : 1\n}\n"},
+		{"BlankLines", "{\"a\": 1,\n\n\n\"b\": 2,\n\n// c\n\n\"c\": 3}", "{\n  \"a\": 1,\n\n  \"b\": 2,\n\n  // c\n\n  \"c\": 3\n}\n"},
+		{"NoLeadingBlankLine", "{\n\n\"a\": 1\n\n}", "{\n  \"a\": 1\n}\n"},
+		{"DanglingComments", "[1,\n// a\n\n// b\n]", "[\n  1,\n  // a\n\n  // b\n]\n"},
+		{"TrailingCommas", `{"a": [1,],}`, "{\n  \"a\": [\n    1,\n  ],\n}\n"},
+		{"DocumentComments", "// a\n\n/* b */ {} // c\n/* d */", "// a\n\n/* b */ {} // c\n/* d */\n"},
+		{"CRLF", "{\r\n  // a\r\n  \"a\": 1 // b\r\n}\r\n", "{\n  // a\n  \"a\": 1 // b\n}\n"},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Format([]byte(tt.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(got))
+			again, err := Format(got)
+			require.NoError(t, err)
+			assert.Equal(t, string(got), string(again), "not idempotent")
+		})
+	}
+	for _, tt := range [...]struct {
+		Name string
+		Data []byte
+	}{
+		{"Small", _small},
+		{"Medium", _medium},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Format(tt.Data)
+			require.NoError(t, err)
+			again, err := Format(got)
+			require.NoError(t, err)
+			assert.Equal(t, string(got), string(again), "not idempotent")
+			var want, v map[string]any
+			require.NoError(t, Unmarshal(tt.Data, &want))
+			require.NoError(t, Unmarshal(got, &v))
+			assert.Equal(t, want, v)
+			assert.Equal(t, len(collectComments(t, tt.Data)), len(collectComments(t, got)))
+		})
+	}
+}
+
+func TestIndent(t *testing.T) {
+	t.Parallel()
+	t.Run("Prefix", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		buf.WriteString("x = ")
+		require.NoError(t, Indent(&buf, []byte(`{"a": [1] // c
+}`), "> ", "\t"))
+		assert.Equal(t, "x = {\n> \t\"a\": [\n> \t\t1\n> \t] // c\n> }", buf.String())
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		buf.WriteString("x")
+		err := Indent(&buf, []byte(`{"a": /* }`), "", "  ")
+		var serr *SyntaxError
+		require.ErrorAs(t, err, &serr)
+		assert.Equal(t, "x", buf.String())
+	})
+}
+
+// collectComments returns the comments in the concrete syntax tree of data.
+func collectComments(t *testing.T, data []byte) []string {
+	doc, err := Parse(data)
+	require.NoError(t, err)
+	var c []string
+	add := func(tr []Trivia) {
+		for _, t := range tr {
+			if t.Kind != TriviaWhitespace {
+				c = append(c, string(bytes.TrimRight(t.Raw, "\r")))
+			}
+		}
+	}
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		add(n.Leading)
+		for _, m := range n.Members {
+			add(m.Key.Leading)
+			add(m.BeforeColon)
+			walk(m.Value)
+			add(m.BeforeComma)
+			add(m.Trailing)
+		}
+		for _, e := range n.Elements {
+			walk(e.Value)
+			add(e.BeforeComma)
+			add(e.Trailing)
+		}
+		add(n.End)
+	}
+	walk(doc.Value)
+	add(doc.End)
+	return c
+}
+
+func FuzzFormat(f *testing.F) {
+	for _, data := range [...][]byte{_small, _medium, []byte("[1 // a\n, /* b */ 2,]")} {
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		got, err := Format(data)
+		if err != nil {
+			return
+		}
+		again, err := Format(got)
+		require.NoError(t, err)
+		require.Equal(t, string(got), string(again), "not idempotent")
+		require.Equal(t, collectComments(t, data), collectComments(t, got))
+	})
+}
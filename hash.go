@@ -0,0 +1,48 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import "bytes"
+
+// SanitizeHashComments is like [Sanitize], but it also removes the line
+// comments starting with '#', as used by many configuration files (e.g. the
+// ones converted from YAML or written following the Hjson conventions).
+//
+// As for the other comments, a '#' inside a string is preserved.
+func SanitizeHashComments(data []byte) ([]byte, error) {
+	return stripAll(data, &scanner{hash: true}, false)
+}
+
+// UnmarshalHashComments is like [Unmarshal], but it also removes the line
+// comments starting with '#', as done by [SanitizeHashComments].
+//
+// As [Unmarshal], it sanitizes the data only if it contains any comment
+// outside of strings, including the ones starting with '#'.
+func UnmarshalHashComments(data []byte, v any) error {
+	var s *scanner
+	if hasComments(data, true) {
+		s = &scanner{hash: true}
+	}
+	return unmarshal(data, v, s)
+}
+
+// HasHashCommentRunes is like [HasCommentRunes], but it also returns true if
+// the data contains any '#' character.
+//
+// Caveat: as for [HasCommentRunes], a '#' inside a string as in
+// '{"color": "#fff"}' is reported as a comment rune.
+func HasHashCommentRunes(data []byte) bool {
+	return bytes.IndexByte(data, '#') >= 0 || HasCommentRunes(data)
+}
@@ -0,0 +1,95 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeHashComments(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{"Line", "# comment\n{\"a\": 1}", "\n{\"a\": 1}"},
+		{"Trailing", "{\"a\": 1} # comment", `{"a": 1} `},
+		{"Mixed", "{\n# a\n\"a\": 1, // b\n\"b\": /* c */ 2\n}", "{\n\n\"a\": 1, \n\"b\":  2\n}"},
+		{"String", `{"color": "#fff"}`, `{"color": "#fff"}`},
+		{"InComment", "[1 /* # */, 2] // #\n", "[1 , 2] \n"},
+		{"CommentInHash", "[1] # /* \n[2]", "[1] \n[2]"},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			s, err := SanitizeHashComments([]byte(tt.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(s))
+		})
+	}
+	t.Run("Default", func(t *testing.T) {
+		t.Parallel()
+		s, err := Sanitize([]byte(`{"a": 1} # comment`))
+		require.NoError(t, err)
+		assert.Equal(t, `{"a": 1} # comment`, string(s), "'#' must not start a comment by default")
+	})
+}
+
+func TestUnmarshalHashComments(t *testing.T) {
+	t.Parallel()
+	t.Run("Comments", func(t *testing.T) {
+		t.Parallel()
+		var v struct{ A, B string }
+		require.NoError(t, UnmarshalHashComments([]byte("# a\n{\"a\": \"#1\", // b\n\"b\": \"2\"}"), &v))
+		assert.Equal(t, "#1", v.A)
+		assert.Equal(t, "2", v.B)
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		var v struct{ A int }
+		err := UnmarshalHashComments([]byte("# a\n# b\n{\"a\": \"x\"}"), &v)
+		var perr *PositionError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 3, perr.Line)
+	})
+}
+
+func TestHasHashCommentRunes(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want bool
+	}{
+		{"Hash", "# comment\n{}", true},
+		{"Line", "// comment\n{}", true},
+		{"Block", "/* comment */{}", true},
+		{"None", `{"a": 1}`, false},
+		{"HashInString", `{"color": "#fff"}`, true},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.Want, HasHashCommentRunes([]byte(tt.Data)))
+		})
+	}
+}
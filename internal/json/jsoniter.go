@@ -17,8 +17,76 @@
 
 package json
 
-import jsoniter "github.com/json-iterator/go"
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"regexp"
+	"strconv"
+
+	jsoniter "github.com/json-iterator/go"
+)
 
 // Unmarshal is the function used to unmarshal JSONC data using the jsoniter
 // library.
 var Unmarshal = jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal
+
+// Marshal is the function used to marshal Go values using the jsoniter
+// library.
+var Marshal = jsoniter.ConfigCompatibleWithStandardLibrary.Marshal
+
+// MarshalNoEscapeHTML is like Marshal, but it does not escape the
+// characters <, > and & in strings.
+var MarshalNoEscapeHTML = jsoniter.Config{
+	SortMapKeys:            true,
+	ValidateJsonRawMessage: true,
+}.Froze().Marshal
+
+// MarshalIndent is like Marshal, but it indents the output.
+//
+// The output is indented by the standard library, since jsoniter supports
+// neither the prefix nor indentations other than spaces.
+func MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	b, err := Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := stdjson.Indent(&buf, b, prefix, indent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// jsoniterErrorRegexp matches the position reported by jsoniter errors, which
+// contain the offset relative to a window of the data ("parsing") and a
+// bigger window around it ("context").
+var jsoniterErrorRegexp = regexp.MustCompile(`(?s)error found in #(\d+) byte of \.\.\.\|(.*)\|\.\.\., bigger context \.\.\.\|(.*)\|\.\.\.$`)
+
+// ErrorOffset returns the offset of the byte of data at which err occurred
+// and the path of the related field (if any). It returns false if err does
+// not report any position.
+//
+// jsoniter does not report the absolute offset of the errors, so it is
+// inferred looking for the data windows included in the error message: it
+// returns false if they are found more than once, as in similar values,
+// since the error cannot be located. The field path is not reported.
+func ErrorOffset(data []byte, err error) (offset int64, field string, ok bool) {
+	m := jsoniterErrorRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, "", false
+	}
+	if bytes.Count(data, []byte(m[3])) != 1 {
+		return 0, "", false
+	}
+	ctx := bytes.Index(data, []byte(m[3]))
+	if bytes.Count(data[ctx:ctx+len(m[3])], []byte(m[2])) != 1 {
+		return 0, "", false
+	}
+	peek := bytes.Index(data[ctx:], []byte(m[2]))
+	// n is the number of bytes read from the start of the parsing window
+	return lastRead(int64(ctx + peek + n)), "", true
+}
@@ -17,8 +17,47 @@
 
 package json
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
 
 // Unmarshal is the function used to unmarshal JSONC data using the standard
 // library.
 var Unmarshal = json.Unmarshal
+
+// Marshal is the function used to marshal Go values using the standard
+// library.
+var Marshal = json.Marshal
+
+// MarshalIndent is like Marshal, but it indents the output.
+var MarshalIndent = json.MarshalIndent
+
+// MarshalNoEscapeHTML is like Marshal, but it does not escape the
+// characters <, > and & in strings.
+func MarshalNoEscapeHTML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// ErrorOffset returns the offset of the byte of data at which err occurred
+// and the path of the related field (if any). It returns false if err does
+// not report any position.
+func ErrorOffset(data []byte, err error) (offset int64, field string, ok bool) {
+	var serr *json.SyntaxError
+	if errors.As(err, &serr) {
+		// the error occurred after reading Offset bytes
+		return lastRead(serr.Offset), "", true
+	}
+	var terr *json.UnmarshalTypeError
+	if errors.As(err, &terr) {
+		return lastRead(terr.Offset), terr.Field, true
+	}
+	return 0, "", false
+}
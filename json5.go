@@ -0,0 +1,326 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SanitizeJSON5 converts JSON5 data to standard JSON.
+//
+// Besides removing the comments and the trailing commas (see [SanitizeJWCC]),
+// it converts the JSON5 constructs to their JSON equivalent:
+//
+//   - unquoted identifier keys are quoted
+//   - single-quoted strings are converted to double-quoted strings
+//   - hexadecimal numbers are converted to decimal numbers
+//   - the leading '+' of numbers is removed
+//   - leading and trailing decimal points are completed with a zero
+//   - line continuations in strings are removed
+//   - the escape sequences not supported by JSON (e.g. '\x41', '\v', '\0')
+//     are converted to their '\uXXXX' equivalent
+//   - the JSON5 white spaces (e.g. '\v', U+00A0, U+FEFF) are replaced with
+//     spaces
+//
+// It returns a [*SyntaxError] for the constructs that cannot be represented
+// in JSON, as Infinity and NaN, reporting their position in the original
+// data.
+//
+// NOTE: as [Sanitize], it does not check whether the result is valid JSON.
+func SanitizeJSON5(data []byte) ([]byte, error) {
+	out, _, err := sanitizeJSON5(data, &scanner{}, false)
+	return out, err
+}
+
+// UnmarshalJSON5 is like [Unmarshal], but it converts the JSON5 data to JSON
+// before unmarshaling it, as done by [SanitizeJSON5].
+func UnmarshalJSON5(data []byte, v any) error {
+	out, original, err := sanitizeJSON5(data, &scanner{}, false)
+	if err != nil {
+		return err
+	}
+	return unmarshalSanitized(data, out, original, v)
+}
+
+// sanitizeJSON5 converts data to JSON using s to remove the comments and the
+// trailing commas, returning also the function mapping the offsets of the
+// result to the ones of data. If blank is true, the comments are replaced
+// with spaces (see [SanitizeKeepOffsets]).
+func sanitizeJSON5(data []byte, s *scanner, blank bool) ([]byte, func(int64) int64, error) {
+	var offsets offsetMap
+	s.trailingCommas, s.json5, s.offsets = true, true, &offsets
+	stripped, err := stripAll(data, s, blank)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := json5Converter{
+		data:     data,
+		original: offsets.original,
+		src:      stripped,
+		out:      make([]byte, 0, len(stripped)),
+	}
+	if err := c.convert(); err != nil {
+		return nil, nil, err
+	}
+	original := func(off int64) int64 {
+		return offsets.original(c.offsets.original(off))
+	}
+	return c.out, original, nil
+}
+
+// json5Converter converts JSON5 data without comments to JSON.
+type json5Converter struct {
+	data     []byte            // original data, used to report errors
+	original func(int64) int64 // maps the offsets of src to the ones of data
+	src      []byte            // data without comments
+	out      []byte            // converted data
+	offsets  offsetMap         // maps the offsets of out to the ones of src
+	stack    []byte            // open objects and arrays
+	key      bool              // an object key is expected
+}
+
+func (c *json5Converter) convert() error {
+	for i := 0; i < len(c.src); {
+		c.offsets.set(int64(len(c.out)), int64(i-len(c.out)))
+		var err error
+		switch b := c.src[i]; {
+		case b == '{' || b == '[':
+			c.stack = append(c.stack, b)
+			c.key = b == '{'
+			c.out = append(c.out, b)
+			i++
+		case b == '}' || b == ']':
+			if len(c.stack) > 0 {
+				c.stack = c.stack[:len(c.stack)-1]
+			}
+			c.key = false
+			c.out = append(c.out, b)
+			i++
+		case b == ',':
+			c.key = len(c.stack) > 0 && c.stack[len(c.stack)-1] == '{'
+			c.out = append(c.out, b)
+			i++
+		case b == ':':
+			c.key = false
+			c.out = append(c.out, b)
+			i++
+		case b == '"' || b == '\'':
+			i, err = c.string(i)
+			c.key = false
+		case isSpace(b):
+			c.out = append(c.out, b)
+			i++
+		case b == '+' || b == '-' || b == '.' || ('0' <= b && b <= '9'):
+			i, err = c.number(i)
+		default:
+			r, size := utf8.DecodeRune(c.src[i:])
+			switch {
+			case isJSON5Space(r):
+				c.out = append(c.out, ' ')
+				i += size
+			case isIdentifierStart(r):
+				i, err = c.identifier(i)
+				c.key = false
+			default:
+				// let the JSON library report the error
+				c.out = append(c.out, c.src[i:i+size]...)
+				i += size
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// identifier converts the identifier starting at i, quoting it if it is an
+// object key.
+func (c *json5Converter) identifier(i int) (int, error) {
+	end := i
+	for end < len(c.src) {
+		r, size := utf8.DecodeRune(c.src[end:])
+		if !isIdentifierPart(r) {
+			break
+		}
+		end += size
+	}
+	id := c.src[i:end]
+	switch {
+	case c.key:
+		c.out = append(c.out, '"')
+		c.out = append(c.out, id...)
+		c.out = append(c.out, '"')
+	case isNonFinite(id):
+		return end, c.error(i, fmt.Sprintf("%s cannot be represented in JSON", id))
+	default:
+		c.out = append(c.out, id...)
+	}
+	return end, nil
+}
+
+// number converts the number starting at i.
+func (c *json5Converter) number(i int) (int, error) {
+	end := i
+	for end < len(c.src) && isNumberPart(c.src[end]) {
+		end++
+	}
+	num := c.src[i:end]
+	if num[0] == '+' || num[0] == '-' {
+		if num[0] == '-' {
+			c.out = append(c.out, '-')
+		}
+		num = num[1:]
+	}
+	switch {
+	case isNonFinite(num):
+		return end, c.error(i, fmt.Sprintf("%s cannot be represented in JSON", c.src[i:end]))
+	case len(num) > 1 && num[0] == '0' && (num[1] == 'x' || num[1] == 'X'):
+		n, err := strconv.ParseUint(string(num[2:]), 16, 64)
+		if err != nil {
+			return end, c.error(i, fmt.Sprintf("invalid hexadecimal number %s", c.src[i:end]))
+		}
+		c.out = strconv.AppendUint(c.out, n, 10)
+		return end, nil
+	}
+	mantissa, exponent := num, []byte(nil)
+	if e := bytes.IndexAny(num, "eE"); e >= 0 {
+		mantissa, exponent = num[:e], num[e:]
+	}
+	if len(mantissa) > 0 && mantissa[0] == '.' {
+		c.out = append(c.out, '0')
+	}
+	c.out = append(c.out, mantissa...)
+	if len(mantissa) > 0 && mantissa[len(mantissa)-1] == '.' {
+		c.out = append(c.out, '0')
+	}
+	c.out = append(c.out, exponent...)
+	return end, nil
+}
+
+// string converts the string starting at i to a double-quoted string.
+func (c *json5Converter) string(i int) (int, error) {
+	q := c.src[i]
+	c.out = append(c.out, '"')
+	for i++; i < len(c.src); {
+		switch b := c.src[i]; b {
+		case q:
+			c.out = append(c.out, '"')
+			return i + 1, nil
+		case '"':
+			c.out = append(c.out, '\\', '"')
+			i++
+		case '\\':
+			var err error
+			if i, err = c.escape(i); err != nil {
+				return i, err
+			}
+		default:
+			c.out = append(c.out, b)
+			i++
+		}
+	}
+	// unreachable: unterminated strings are reported by the scanner
+	return i, nil
+}
+
+// escape converts the escape sequence starting at i.
+func (c *json5Converter) escape(i int) (int, error) {
+	if i+1 >= len(c.src) {
+		return i + 1, nil
+	}
+	switch e := c.src[i+1]; e {
+	case '"', '\\', '/', 'b', 'f', 'n', 'r', 't', 'u':
+		c.out = append(c.out, '\\', e)
+		return i + 2, nil
+	case 'v':
+		c.out = append(c.out, `\u000b`...)
+		return i + 2, nil
+	case '0':
+		if i+2 < len(c.src) && '0' <= c.src[i+2] && c.src[i+2] <= '9' {
+			return i, c.error(i, reasonInvalidEscape)
+		}
+		c.out = append(c.out, `\u0000`...)
+		return i + 2, nil
+	case 'x':
+		if i+4 > len(c.src) || !isHex(c.src[i+2]) || !isHex(c.src[i+3]) {
+			return i, c.error(i, reasonInvalidEscape)
+		}
+		c.out = append(c.out, `\u00`...)
+		c.out = append(c.out, c.src[i+2:i+4]...)
+		return i + 4, nil
+	case '\n':
+		return i + 2, nil // line continuation
+	case '\r':
+		if i+2 < len(c.src) && c.src[i+2] == '\n' {
+			return i + 3, nil
+		}
+		return i + 2, nil
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return i, c.error(i, reasonInvalidEscape)
+	}
+	r, size := utf8.DecodeRune(c.src[i+1:])
+	if !isJSON5LineTerminator(r) {
+		// any other character is escaped as itself (e.g. \' and \a)
+		c.out = append(c.out, c.src[i+1:i+1+size]...)
+	}
+	return i + 1 + size, nil
+}
+
+// error returns a [*SyntaxError] for the byte of src at the given offset.
+func (c *json5Converter) error(off int, reason string) error {
+	return newSyntaxError(c.data, c.original(int64(off)), reason)
+}
+
+// isNonFinite reports whether b is one of the JSON5 non-finite numbers.
+func isNonFinite(b []byte) bool {
+	return string(b) == "Infinity" || string(b) == "NaN"
+}
+
+func isNumberPart(b byte) bool {
+	return '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' ||
+		b == '.' || b == '+' || b == '-'
+}
+
+func isHex(b byte) bool {
+	return '0' <= b && b <= '9' || 'a' <= b && b <= 'f' || 'A' <= b && b <= 'F'
+}
+
+// isIdentifierStart reports whether r can start an ECMAScript identifier.
+func isIdentifierStart(r rune) bool {
+	return r == '$' || r == '_' || unicode.IsLetter(r) || unicode.Is(unicode.Nl, r)
+}
+
+// isIdentifierPart reports whether r can be part of an ECMAScript
+// identifier.
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) ||
+		r == '\u200c' || r == '\u200d'
+}
+
+// isJSON5Space reports whether r is a JSON5 white space not allowed by JSON.
+func isJSON5Space(r rune) bool {
+	return r == '\v' || r == '\f' || r == '\ufeff' || isJSON5LineTerminator(r) || unicode.Is(unicode.Zs, r)
+}
+
+// isJSON5LineTerminator reports whether r is a JSON5 line terminator, except
+// for '\n'.
+func isJSON5LineTerminator(r rune) bool {
+	return r == '\r' || r == '\u2028' || r == '\u2029'
+}
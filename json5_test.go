@@ -0,0 +1,114 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeJSON5(t *testing.T) {
+	t.Parallel()
+	t.Run("Example", func(t *testing.T) {
+		t.Parallel()
+		var want, got map[string]any
+		require.NoError(t, json.Unmarshal(_json5JSON, &want))
+		require.NoError(t, UnmarshalJSON5(_json5, &got))
+		assert.Equal(t, want, got)
+	})
+	t.Run("JSONC", func(t *testing.T) {
+		t.Parallel()
+		for _, tt := range [...]struct {
+			Data []byte
+			Type any
+		}{
+			{_small, Small{}},
+			{_medium, Medium{}},
+		} {
+			switch j := tt.Type.(type) {
+			case Small:
+				require.NoError(t, UnmarshalJSON5(tt.Data, &j))
+				FieldsValue(t, j)
+			case Medium:
+				require.NoError(t, UnmarshalJSON5(tt.Data, &j))
+				FieldsValue(t, j)
+			}
+		}
+	})
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{"UnquotedKeys", `{a: 1, $b_2: 2, ñ: 3, 'c': 4}`, `{"a": 1, "$b_2": 2, "ñ": 3, "c": 4}`},
+		{"ReservedKeys", `{true: true, null: null}`, `{"true": true, "null": null}`},
+		{"SingleQuotes", `['a "b" \'c\' // d']`, `["a \"b\" 'c' // d"]`},
+		{"Numbers", `[0x1F, -0XA, +1, .5, 5., -.5e3, 5.e-1, 1e5]`, `[31, -10, 1, 0.5, 5.0, -0.5e3, 5.0e-1, 1e5]`},
+		{"Escapes", `["\x41\v\0\a\/"]`, `["\u0041\u000b\u0000a\/"]`},
+		{"LineContinuation", "['a\\\nb\\\r\nc\\ d']", `["abcd"]`},
+		{"Whitespace", "{\va:\f1\u00a0}\ufeff", `{ "a": 1 } `},
+		{"Comments", "{a: /* 'x' */ 'y', // z\n b: [1,],}", "{\"a\":  \"y\", \n \"b\": [1]}"},
+		{"CommentInSingleQuotes", `['// not a comment']`, `["// not a comment"]`},
+		{"CommentEndingWithCR", "{a: 1, // c\rb: 2}", "{\"a\": 1, \r\"b\": 2}"},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			s, err := SanitizeJSON5([]byte(tt.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(s))
+		})
+	}
+}
+
+func TestSanitizeJSON5Error(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want SyntaxError
+	}{
+		{"Infinity", "{\n  /* c */ a: Infinity}", SyntaxError{"Infinity cannot be represented in JSON", 15, 2, 14}},
+		{"NegativeInfinity", "[1, -Infinity]", SyntaxError{"-Infinity cannot be represented in JSON", 4, 1, 5}},
+		{"NaN", "// c\n[NaN]", SyntaxError{"NaN cannot be represented in JSON", 6, 2, 2}},
+		{"Hex", "[0xZ]", SyntaxError{"invalid hexadecimal number 0xZ", 1, 1, 2}},
+		{"HexEscape", `['\xZZ']`, SyntaxError{"invalid escape sequence in string", 2, 1, 3}},
+		{"OctalEscape", `['\01']`, SyntaxError{"invalid escape sequence in string", 2, 1, 3}},
+		{"UnterminatedString", `['a]`, SyntaxError{reasonUnterminatedString, 1, 1, 2}},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := SanitizeJSON5([]byte(tt.Data))
+			var serr *SyntaxError
+			require.ErrorAs(t, err, &serr)
+			assert.Equal(t, tt.Want, *serr)
+		})
+	}
+	t.Run("Unmarshal", func(t *testing.T) {
+		t.Parallel()
+		var v struct{ A int }
+		err := UnmarshalJSON5([]byte("{\n  // c\n  a: 'x',\n}"), &v)
+		var perr *PositionError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 3, perr.Line)
+	})
+}
@@ -16,107 +16,404 @@
 
 import (
 	"bytes"
-	"errors"
 	"unicode/utf8"
 
 	"github.com/marcozac/go-jsonc/internal/json"
 )
 
-// ErrInvalidUTF8 is returned by Sanitize if the data is not valid UTF-8.
-var ErrInvalidUTF8 = errors.New("jsonc: invalid UTF-8")
-
 // Sanitize removes all comments from JSONC data.
-// It returns [ErrInvalidUTF8] if the data is not valid UTF-8.
+// It returns [ErrInvalidUTF8] if the data is not valid UTF-8 and a
+// [*SyntaxError] if a comment block or a string is not terminated or if a
+// stray comment block terminator ("*/") is found.
 //
 // NOTE: it does not checks whether the data is valid JSON or not.
 func Sanitize(data []byte) ([]byte, error) {
-	if !utf8.Valid(data) {
-		return nil, ErrInvalidUTF8
+	return stripAll(data, &scanner{}, false)
+}
+
+// SanitizeStrict is like [Sanitize], but it also returns a [*SyntaxError] if
+// a '/' or a '*' outside of strings and comments is not part of a comment
+// delimiter. [Sanitize] silently removes them instead, possibly turning a typo
+// (e.g. '{"a": 1 / 2}') into different but still valid JSON.
+func SanitizeStrict(data []byte) ([]byte, error) {
+	return stripAll(data, &scanner{strict: true}, false)
+}
+
+// SanitizeKeepOffsets is like [Sanitize], but it replaces the comments with
+// spaces instead of removing them. New lines are preserved, so that the byte
+// offsets, the line and the column numbers of the returned data match the
+// ones of the original JSONC data.
+//
+// This allows to map the errors reported by any JSON library (e.g. the
+// offset of a json.SyntaxError) directly to the original data.
+func SanitizeKeepOffsets(data []byte) ([]byte, error) {
+	return stripAll(data, &scanner{}, true)
+}
+
+// AppendSanitize appends to dst the JSONC data in src without comments and
+// returns the extended buffer. It reports the same errors as [Sanitize],
+// returning dst unchanged in such a case.
+//
+// It does not allocate if dst has enough capacity (at most len(src) bytes
+// are appended), so that the buffers can be reused across calls:
+//
+//	buf := pool.Get().([]byte)
+//	buf, err := jsonc.AppendSanitize(buf[:0], data)
+//
+// dst must not overlap src, except for the case handled by [SanitizeInPlace].
+func AppendSanitize(dst, src []byte) ([]byte, error) {
+	var s scanner
+	out, err := s.stripAll(dst, src, false)
+	if err != nil {
+		return dst, err
+	}
+	return out, nil
+}
+
+// SanitizeInPlace is like [Sanitize], but it removes the comments in place,
+// overwriting data, and returns the sanitized prefix of data. It never
+// allocates, since the sanitized data is never longer than the original one.
+//
+// If an error is returned, the content of data is undefined.
+func SanitizeInPlace(data []byte) ([]byte, error) {
+	out, err := AppendSanitize(data[:0], data)
+	if err != nil {
+		return nil, err
 	}
-	return sanitize(data), nil
+	return out, nil
+}
+
+// stripAll removes all comments from data using s, returning a new slice.
+// See [scanner.stripAll].
+func stripAll(data []byte, s *scanner, blank bool) ([]byte, error) {
+	out, err := s.stripAll(make([]byte, 0, len(data)), data, blank)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
 const (
-	_hasCommentRunes byte = 1 << iota
-	_isString
+	_isString byte = 1 << iota
 	_isCommentLine
 	_isCommentBlock
 	_checkNext
+	_checkStar
+	_isSingleQuoted
+)
+
+// scanner holds the state of the comments removal state machine. It is
+// shared by [Sanitize] and the streaming readers, so that the state can be
+// carried across multiple chunks of data.
+type scanner struct {
+	state          byte
+	strict         bool     // report stray '/' and '*'
+	trailingCommas bool     // remove trailing commas (strip only)
+	json5          bool     // single-quoted strings and JSON5 line terminators
+	hash           bool     // '#' line comments
+	skipUTF8       bool     // do not validate the data
+	pos            position // position of the next rune
+	start          position // start of the current string or comment
+	err            error    // first error found
+	invalidUTF8    bool     // invalid UTF-8 after the first syntax error
+
+	// offsets, if not nil, records the bytes removed by strip.
+	offsets *offsetMap
+}
+
+// Stop tables mark, for each state of the scanner, the bytes that may change
+// the state: all the other bytes are copied (or removed) in bulk, without
+// decoding them. The bytes that are not ASCII are always marked, so that the
+// UTF-8 validation and the column count are done in the same pass.
+var (
+	stopDefault      = stopTable(`"'/*#,`)
+	stopString       = stopTable(`"'\`)
+	stopCommentLine  = stopTable("\n\r")
+	stopCommentBlock = stopTable("*")
 )
 
-func sanitize(data []byte) []byte {
-	var state byte
-	return bytes.Map(func(r rune) rune {
-		checkNext := state&_checkNext != 0
-		state &^= _checkNext
+// stopTable returns a stop table marking the given ASCII characters, the new
+// line and all the non-ASCII bytes.
+func stopTable(chars string) *[256]bool {
+	var t [256]bool
+	for i := 0; i < len(chars); i++ {
+		t[chars[i]] = true
+	}
+	t['\n'] = true
+	for c := utf8.RuneSelf; c < len(t); c++ {
+		t[c] = true
+	}
+	return &t
+}
+
+// stops returns the stop table of the current state.
+func (s *scanner) stops() *[256]bool {
+	switch {
+	case s.state&_isCommentLine != 0:
+		return stopCommentLine
+	case s.state&_isCommentBlock != 0:
+		return stopCommentBlock
+	case s.state&_isString != 0:
+		return stopString
+	}
+	return stopDefault
+}
+
+// next returns r if it must be written to the output, or -1 if it is part of
+// a comment and must be skipped. It does not advance the position, that is
+// the one of r.
+func (s *scanner) next(r rune) rune {
+	checkNext := s.state&_checkNext != 0
+	checkStar := s.state&_checkStar != 0
+	s.state &^= _checkNext | _checkStar
+	switch {
+	case s.state&_isCommentLine != 0:
+		if r != '\n' && !(s.json5 && isJSON5LineTerminator(r)) {
+			return -1 // mark rune for skip
+		}
+		s.state &^= _isCommentLine
+	case s.state&_isCommentBlock != 0:
+		switch {
+		case r == '*':
+			s.state |= _checkNext
+		case r == '/' && checkNext:
+			s.state &^= _isCommentBlock
+		}
+		return -1 // mark rune for skip
+	case s.state&_isString != 0:
+		switch {
+		case checkNext:
+			// escaped rune: a backslash, a quote or any other escape
+			// character (the hex digits of \uXXXX cannot change the state)
+		case r == '\\':
+			s.state |= _checkNext
+		case r == '"' && s.state&_isSingleQuoted == 0,
+			r == '\'' && s.state&_isSingleQuoted != 0:
+			s.state &^= _isString | _isSingleQuoted
+		}
+	default:
+		if s.strict {
+			s.checkStray(checkNext && r != '/' && r != '*', checkStar && r != '/')
+		}
 		switch r {
-		case '\n':
-			state &^= _isCommentLine
-		case '\\':
-			if state&_isString != 0 {
-				state |= _checkNext
-			}
 		case '"':
-			if state&_isString != 0 {
-				if checkNext { // escaped quote
-					break // switch => write rune
-				}
-				state &^= _isString
-			} else if state&(_isCommentLine|_isCommentBlock) == 0 {
-				state |= _isString
+			s.state |= _isString
+			s.start = s.pos
+		case '\'':
+			if s.json5 {
+				s.state |= _isString | _isSingleQuoted
+				s.start = s.pos
 			}
-		case '/':
-			if state&_isString != 0 {
-				break // switch => write rune
+		case '#':
+			if s.hash {
+				s.state |= _isCommentLine
+				return -1 // mark rune for skip
 			}
-			if state&_isCommentBlock != 0 {
-				if checkNext {
-					state &^= _isCommentBlock
-				} else {
-					state |= _isCommentLine
-				}
-			} else {
-				if checkNext {
-					state |= _isCommentLine
-				} else {
-					state |= _checkNext
-				}
+		case '/':
+			switch {
+			case checkNext:
+				s.state |= _isCommentLine
+			case checkStar:
+				s.error(s.start, reasonStrayCommentEnd)
+			default:
+				s.state |= _checkNext
+				s.start = s.pos
 			}
 			return -1 // mark rune for skip
 		case '*':
-			if state&_isString != 0 {
-				break // switch => write rune
-			}
 			if checkNext {
-				state |= _isCommentBlock
-			} else if state&_isCommentBlock != 0 {
-				state |= _checkNext
+				s.state |= _isCommentBlock
+			} else {
+				s.state |= _checkStar
+				s.start = s.pos
 			}
 			return -1 // mark rune for skip
 		}
-		if state&(_isCommentLine|_isCommentBlock) != 0 {
-			return -1 // mark rune for skip
+	}
+	return r
+}
+
+// strip appends to dst the data in src without comments and returns the
+// extended buffer. If blank is true, the bytes of the comments are replaced
+// with spaces, except for new lines.
+//
+// The runs of bytes that cannot change the state are skipped using the stop
+// tables and copied (or removed) at once, so that only the delimiters and
+// the non-ASCII runes go through [scanner.next].
+//
+// dst may overlap src (e.g. src[:0]) since the output is never longer than
+// the input. It stops at the first syntax error or, unless s.skipUTF8 is
+// true, at the first invalid UTF-8 sequence, recording [ErrInvalidUTF8].
+func (s *scanner) strip(dst, src []byte, blank bool) []byte {
+	base := len(dst)
+	for i := 0; i < len(src); {
+		if s.state&(_checkNext|_checkStar) == 0 {
+			stops := s.stops()
+			j := i
+			for j < len(src) && !stops[src[j]] {
+				j++
+			}
+			if j > i {
+				s.pos.off += int64(j - i)
+				s.pos.col += j - i
+				keep := s.state&(_isCommentLine|_isCommentBlock) == 0
+				dst = s.emit(dst, src[i:j], keep, blank, base)
+				if i = j; i == len(src) {
+					break
+				}
+			}
+		}
+		r, size := rune(src[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRune(src[i:])
+			if r == utf8.RuneError && size == 1 && !s.skipUTF8 {
+				s.err = ErrInvalidUTF8
+				break
+			}
 		}
-		return r
-	}, data)
+		keep := s.next(r) >= 0
+		if s.err != nil {
+			// src[i:] is not overwritten yet, even if dst overlaps it
+			s.invalidUTF8 = !s.skipUTF8 && !utf8.Valid(src[i:])
+			break
+		}
+		s.pos.advance(r, size)
+		if keep && r == ',' && s.trailingCommas && s.state&_isString == 0 {
+			keep = !s.isTrailingComma(src[i+1:])
+		}
+		dst = s.emit(dst, src[i:i+size], keep, blank, base)
+		i += size
+	}
+	return dst
+}
+
+// emit appends b to dst if keep is true. Otherwise, b is replaced with
+// spaces if blank is true or removed, recording it in s.offsets. base is the
+// length of dst before the call to strip.
+func (s *scanner) emit(dst, b []byte, keep, blank bool, base int) []byte {
+	switch {
+	case keep:
+		return append(dst, b...)
+	case blank:
+		for _, c := range b {
+			if c != '\n' {
+				c = ' '
+			}
+			dst = append(dst, c)
+		}
+	case s.offsets != nil:
+		s.offsets.remove(int64(len(dst)-base), int64(len(b)))
+	}
+	return dst
+}
+
+// stripAll appends to dst the data in src without comments as done by strip
+// and reports the first error found. Invalid UTF-8 takes precedence over the
+// syntax errors, as if src was validated before being stripped.
+func (s *scanner) stripAll(dst, src []byte, blank bool) ([]byte, error) {
+	dst = s.strip(dst, src, blank)
+	err := s.eof()
+	if err != nil && s.invalidUTF8 {
+		err = ErrInvalidUTF8
+	}
+	return dst, err
+}
+
+// isTrailingComma reports whether the comma preceding data is followed only
+// by white spaces and comments before the end of an array or an object.
+func (s *scanner) isTrailingComma(data []byte) bool {
+	la := scanner{state: s.state, json5: s.json5, hash: s.hash} // lookahead
+	for i := 0; i < len(data); {
+		r, size := rune(data[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRune(data[i:])
+		}
+		i += size
+		if la.next(r) < 0 || (r < utf8.RuneSelf && isSpace(byte(r))) {
+			continue
+		}
+		return r == ']' || r == '}'
+	}
+	return false
+}
+
+// eof reports the first error found by the scanner or, if the data ended in
+// the middle of a comment block or a string, the related error.
+func (s *scanner) eof() error {
+	switch {
+	case s.err != nil:
+		return s.err
+	case s.state&_isCommentBlock != 0:
+		s.error(s.start, reasonUnterminatedComment)
+	case s.state&_isString != 0:
+		s.error(s.start, reasonUnterminatedString)
+	case s.strict:
+		s.checkStray(s.state&_checkNext != 0, s.state&_checkStar != 0)
+	}
+	return s.err
+}
+
+// checkStray records an error if the last rune was a stray '/' or '*'.
+func (s *scanner) checkStray(slash, star bool) {
+	switch {
+	case slash:
+		s.error(s.start, reasonStraySlash)
+	case star:
+		s.error(s.start, reasonStrayStar)
+	}
+}
+
+// error records a syntax error at the given position, unless another error
+// has already been found.
+func (s *scanner) error(pos position, reason string) {
+	if s.err == nil {
+		s.err = &SyntaxError{
+			Reason: reason,
+			Offset: pos.off,
+			Line:   pos.line + 1,
+			Column: pos.col + 1,
+		}
+	}
+}
+
+// position is the position of a rune in the data.
+type position struct {
+	off  int64 // byte offset
+	line int   // line number (0-based)
+	col  int   // column number in runes (0-based)
+}
+
+// advance moves the position after r, encoded in size bytes.
+func (p *position) advance(r rune, size int) {
+	p.off += int64(size)
+	if r == '\n' {
+		p.line++
+		p.col = 0
+	} else {
+		p.col++
+	}
 }
 
 // Unmarshal parses the JSONC-encoded data and stores the result in the value
 // pointed by v removing all comments from the data (if any).
 //
-// It uses [HasCommentRunes] to check whether the data contains any comment.
+// It uses [HasComments] to check whether the data contains any comment.
 // Note that this operation is as expensive as the larger the data. On small
 // data sets it just adds a small overhead to the unmarshaling process, but
 // on large data sets it may have a significant impact on performance. In such
 // cases, it may be more efficient to call [Sanitize] and then the standard
 // (or any other) library directly.
 //
-// If the data contains comment runes, it calls [Sanitize] to remove them and
-// returns [ErrInvalidUTF8] if the data is not valid UTF-8. Note that if no
+// If the data contains comments, it calls [Sanitize] to remove them and
+// returns [ErrInvalidUTF8] if the data is not valid UTF-8 or a [*SyntaxError]
+// if the comments or the strings are malformed. Note that if no
 // comments are found, it is assumed that the given data is valid JSON-encoded
 // and the UTF-8 validity is not checked.
 //
-// Any error is reported from [json.Unmarshal] as is.
+// The errors reported by [json.Unmarshal] that carry a position (as
+// json.SyntaxError and json.UnmarshalTypeError or their equivalents in the
+// alternative libraries) are wrapped in a [*PositionError], which reports
+// the position in the original JSONC data. Any other error is returned as is.
 //
 // It uses the standard library for unmarshaling by default, but can be
 // configured to use the [jsoniter] or [go-json] library instead by using build
@@ -142,14 +439,36 @@ func sanitize(data []byte) []byte {
 // [jsoniter]: https://github.com/json-iterator/go
 // [go-json]: https://github.com/goccy/go-json
 func Unmarshal(data []byte, v any) error {
-	if HasCommentRunes(data) {
+	var s *scanner
+	if HasComments(data) {
+		s = &scanner{}
+	}
+	return unmarshal(data, v, s)
+}
+
+// unmarshal removes the comments from data using s, if not nil, and
+// unmarshals the result into v.
+func unmarshal(data []byte, v any, s *scanner) error {
+	src := data
+	var offsets offsetMap
+	if s != nil {
+		s.offsets = &offsets
 		var err error
-		data, err = Sanitize(data)
-		if err != nil {
+		if data, err = stripAll(data, s, false); err != nil {
 			return err
 		}
 	}
-	return json.Unmarshal(data, v)
+	return unmarshalSanitized(src, data, offsets.original, v)
+}
+
+// unmarshalSanitized unmarshals data, the sanitized version of src, into v.
+// original maps the offsets of data to the ones of src, to report the errors
+// at their original position.
+func unmarshalSanitized(src, data []byte, original func(int64) int64, v any) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return newPositionError(src, data, original, err)
+	}
+	return nil
 }
 
 // HasCommentRunes returns true if the data contains any comment rune.
@@ -158,7 +477,8 @@ func Unmarshal(data []byte, v any) error {
 // If not, it returns false.
 //
 // Caveat: if the data contains a string that looks like a comment as
-// '{"url": "http://example.com"}', HasCommentRunes returns true.
+// '{"url": "http://example.com"}', HasCommentRunes returns true. Use
+// [HasComments] to ignore the content of the strings.
 //
 // For example, it returns true for the following data:
 //
@@ -180,19 +500,74 @@ func Unmarshal(data []byte, v any) error {
 //
 //	{ "key": "value // comment" }
 func HasCommentRunes(data []byte) bool {
-	var state byte
-	bytes.IndexFunc(data, func(r rune) bool {
-		if state&_checkNext != 0 {
-			if r == '/' || r == '*' {
-				state |= _hasCommentRunes
+	for i := 0; ; {
+		j := bytes.IndexByte(data[i:], '/')
+		if j < 0 {
+			return false
+		}
+		if i += j + 1; i < len(data) && (data[i] == '/' || data[i] == '*') {
+			return true
+		}
+	}
+}
+
+// HasComments returns true if the data contains any comment, that is a "//"
+// or a "/*" outside of strings. Unlike [HasCommentRunes], it does not report
+// the strings that look like a comment as '{"url": "http://example.com"}'.
+//
+// It only looks for the comment openers and the strings delimiters, skipping
+// the content of the strings in bulk, so that its cost is comparable to the
+// one of [HasCommentRunes]. It does not check whether the data is valid JSON
+// or whether the strings and the comments are terminated.
+func HasComments(data []byte) bool {
+	return hasComments(data, false)
+}
+
+// hasCommentsStops marks the bytes that may start a string or a comment.
+var hasCommentsStops = [256]bool{'"': true, '/': true, '#': true}
+
+// hasComments implements [HasComments]. If hash is true, '#' outside of
+// strings is also reported as a comment.
+func hasComments(data []byte, hash bool) bool {
+	for i := 0; i < len(data); i++ {
+		for i < len(data) && !hasCommentsStops[data[i]] {
+			i++
+		}
+		if i == len(data) {
+			return false
+		}
+		switch data[i] {
+		case '"':
+			// skip to the closing quote, ignoring the escaped ones
+			for {
+				j := bytes.IndexByte(data[i+1:], '"')
+				if j < 0 {
+					return false
+				}
+				if i += j + 1; !isEscaped(data, i) {
+					break
+				}
+			}
+		case '/':
+			if i+1 < len(data) && (data[i+1] == '/' || data[i+1] == '*') {
+				return true
+			}
+		case '#':
+			if hash {
 				return true
 			}
-			state &^= _checkNext
-		}
-		if r == '/' {
-			state |= _checkNext
 		}
-		return false
-	})
-	return state&_hasCommentRunes != 0
+	}
+	return false
+}
+
+// isEscaped reports whether the byte of data at index i is preceded by an
+// odd number of backslashes.
+func isEscaped(data []byte, i int) bool {
+	n := 0
+	for i > 0 && data[i-1] == '\\' {
+		n++
+		i--
+	}
+	return n%2 == 1
 }
@@ -42,6 +42,18 @@
 	//go:embed testdata/medium_no_comment_runes.json
 	_mediumNoCommentRunes []byte
 
+	//go:embed testdata/escapes.json
+	_escapes []byte
+
+	//go:embed testdata/escapes_uncommented.json
+	_escapesUncommented []byte
+
+	//go:embed testdata/json5.json5
+	_json5 []byte
+
+	//go:embed testdata/json5.json
+	_json5JSON []byte
+
 	_invalidChar = []byte("\xa5")
 )
 
@@ -113,6 +125,51 @@ func BenchmarkHasCommentRunes(b *testing.B) {
 	}
 }
 
+func TestHasComments(t *testing.T) {
+	t.Parallel()
+	for _, tt := range hasCommentsTests {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.Want, HasComments(tt.Data))
+		})
+	}
+}
+
+var hasCommentsTests = [...]struct {
+	Name string
+	Data []byte
+	Want bool
+}{
+	{"Small/Commented", _small, true},
+	{"Small/Uncommented", _smallUncommented, false},
+	{"Small/NoCommentRunes", _smallNoCommentRunes, false},
+	{"Medium/Commented", _medium, true},
+	{"Medium/Uncommented", _mediumUncommented, false},
+	{"Medium/NoCommentRunes", _mediumNoCommentRunes, false},
+	{"Escapes/Commented", _escapes, true},
+	{"Escapes/Uncommented", _escapesUncommented, false},
+	{"EscapedQuote", []byte(`{"a": "\" // \"", "b": 1}`), false},
+	{"EscapedBackslash", []byte(`{"a": "\\\\"} // comment`), true},
+	{"BlockAfterString", []byte(`{"a": "//"/* comment */}`), true},
+	{"Hash", []byte(`{"a": 1} # comment`), false},
+	{"UnterminatedString", []byte(`{"a": "// comment`), false},
+	{"TrailingSlash", []byte(`{"a": 1}/`), false},
+}
+
+func BenchmarkHasComments(b *testing.B) {
+	for _, tt := range hasCommentsTests[:6] {
+		tt := tt
+		b.Run(tt.Name, func(b *testing.B) {
+			b.RunParallel(func(p *testing.PB) {
+				for p.Next() {
+					assert.Equal(b, tt.Want, HasComments(tt.Data))
+				}
+			})
+		})
+	}
+}
+
 type DataType interface {
 	Small | SmallNoCommentRunes | Medium | MediumNoCommentRunes
 }
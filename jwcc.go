@@ -0,0 +1,36 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+// SanitizeJWCC is like [Sanitize], but it also removes the trailing commas
+// from objects and arrays, as allowed by the JWCC (JSON With Commas and
+// Comments, also known as HuJSON) dialect used by many configuration files
+// (e.g. VS Code's settings.json or tsconfig.json).
+//
+// A comma is considered trailing if it is followed only by white spaces and
+// comments before the closing bracket, as in '[1, /* x */ ]'. Commas inside
+// strings and comments are preserved.
+func SanitizeJWCC(data []byte) ([]byte, error) {
+	return stripAll(data, &scanner{trailingCommas: true}, false)
+}
+
+// UnmarshalJWCC is like [Unmarshal], but it removes the trailing commas from
+// objects and arrays, as done by [SanitizeJWCC].
+//
+// Since trailing commas cannot be detected without scanning the data, it
+// always sanitizes the data before unmarshaling it.
+func UnmarshalJWCC(data []byte, v any) error {
+	return unmarshal(data, v, &scanner{trailingCommas: true})
+}
@@ -0,0 +1,88 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeJWCC(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{"Array", `[1, 2,]`, `[1, 2]`},
+		{"Object", `{"a": 1,}`, `{"a": 1}`},
+		{"BlockComment", `[1, /* x */ ]`, `[1  ]`},
+		{"LineComment", "{\"a\":1,// c\n}", "{\"a\":1\n}"},
+		{"CommentBeforeComma", "[1 /* x */, // y\n]", "[1  \n]"},
+		{"Nested", `{"a": [1, [2,],], "b": {"c": "d",},}`, `{"a": [1, [2]], "b": {"c": "d"}}`},
+		{"NotTrailing", `[1, /* ] */ 2]`, `[1,  2]`},
+		{"String", `["a,]", "b,}" ]`, `["a,]", "b,}" ]`},
+		{"Comment", "[1 /* , */ // ,\n]", "[1  \n]"},
+		{"EOF", `[1,`, `[1,`},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			s, err := SanitizeJWCC([]byte(tt.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(s))
+		})
+	}
+}
+
+// jwccTrailingCommaRegexp matches the last value of objects and arrays
+// spanning multiple lines, to add a trailing comma after it.
+var jwccTrailingCommaRegexp = regexp.MustCompile(`([^\s{\[])(\s*\n\s*[\]}])`)
+
+func TestUnmarshalJWCC(t *testing.T) {
+	t.Parallel()
+	t.Run("Small", func(t *testing.T) {
+		t.Parallel()
+		unmarshalJWCCTest(t, _small, Small{})
+	})
+	t.Run("Medium", func(t *testing.T) {
+		t.Parallel()
+		unmarshalJWCCTest(t, _medium, Medium{})
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		var v any
+		err := UnmarshalJWCC([]byte("{\n  // c\n  \"a\": [1,,],\n}"), &v)
+		var perr *PositionError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 3, perr.Line)
+	})
+}
+
+func unmarshalJWCCTest[T DataType](t *testing.T, data []byte, dt T) {
+	t.Helper()
+	data = jwccTrailingCommaRegexp.ReplaceAll(data, []byte("$1,$2"))
+	j := dt
+	assert.Error(t, Unmarshal(data, &j), "trailing commas must be rejected by Unmarshal")
+	j = dt
+	require.NoError(t, UnmarshalJWCC(data, &j))
+	FieldsValue(t, j)
+}
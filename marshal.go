@@ -0,0 +1,319 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"encoding"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+)
+
+// Marshal returns the JSON encoding of v, as done by json.Marshal, with
+// comments before the members encoding the struct fields tagged with
+// `jsonc:"comment=..."`. The text of the comment is the whole value of the
+// tag following "comment=". For example:
+//
+//	type Config struct {
+//		Port int `json:"port" jsonc:"comment=Port to listen on"`
+//	}
+//
+// Since the output is on a single line, the comments are written as block
+// comments: use [MarshalIndent] to write them on their own lines.
+//
+// The comments are added to the fields of the structs, including the
+// promoted ones, reachable through pointers, interfaces, maps, slices and
+// arrays, unless the type implements json.Marshaler or
+// encoding.TextMarshaler.
+func Marshal(v any) ([]byte, error) {
+	doc, err := marshalDocument(v, commenter{}, true)
+	if err != nil {
+		return nil, err
+	}
+	c := compacter{keepComments: true}
+	c.document(doc)
+	return c.buf, nil
+}
+
+// MarshalIndent is like [Marshal], but it indents the output as done by
+// json.MarshalIndent, writing the comments on their own lines before the
+// members. The comments spanning multiple lines, as
+// `jsonc:"comment=First line\nSecond line"`, are written as block comments,
+// the other ones as line comments.
+func MarshalIndent(v any, prefix, indent string) ([]byte, error) {
+	doc, err := marshalDocument(v, commenter{prefix: prefix, indent: indent, indented: true}, true)
+	if err != nil {
+		return nil, err
+	}
+	f := formatter{prefix: prefix, indent: indent}
+	f.document(doc)
+	return f.buf, nil
+}
+
+// marshalDocument returns the concrete syntax tree of the JSON encoding of
+// v with the comments of the tagged struct fields added by c. If escapeHTML
+// is false, the characters <, > and & in strings are not escaped.
+func marshalDocument(v any, c commenter, escapeHTML bool) (*Document, error) {
+	marshal := json.Marshal
+	if !escapeHTML {
+		marshal = json.MarshalNoEscapeHTML
+	}
+	b, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := Parse(b)
+	if err != nil {
+		return nil, err
+	}
+	c.value(reflect.ValueOf(v), doc.Value, 0)
+	return doc, nil
+}
+
+// commenter adds the comments of the tagged struct fields to a concrete
+// syntax tree.
+type commenter struct {
+	prefix   string
+	indent   string
+	indented bool
+}
+
+// value adds the comments of the struct fields in v to n, its encoding at
+// the given depth.
+func (c *commenter) value(v reflect.Value, n *Node, depth int) {
+	for {
+		if !v.IsValid() || isMarshaler(v) {
+			return
+		}
+		if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+			break
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.Struct && n.Kind == KindObject:
+		fields := cachedFields(v.Type())
+		for _, m := range n.Members {
+			f, ok := fields[m.Name()]
+			if !ok {
+				continue
+			}
+			if f.comment != "" {
+				m.Key.Leading = c.comment(f.comment, depth+1)
+			}
+			if fv, err := v.FieldByIndexErr(f.index); err == nil {
+				c.value(fv, m.Value, depth+1)
+			}
+		}
+	case v.Kind() == reflect.Map && n.Kind == KindObject:
+		values := make(map[string]reflect.Value, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			if name, ok := mapKey(it.Key()); ok {
+				values[name] = it.Value()
+			}
+		}
+		for _, m := range n.Members {
+			c.value(values[m.Name()], m.Value, depth+1)
+		}
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && n.Kind == KindArray:
+		for i, e := range n.Elements {
+			if i < v.Len() {
+				c.value(v.Index(i), e.Value, depth+1)
+			}
+		}
+	}
+}
+
+// comment returns the trivia of the comment with the given text preceding
+// a member at the given depth.
+func (c *commenter) comment(text string, depth int) []Trivia {
+	nl := Trivia{Kind: TriviaWhitespace, Raw: []byte("\n")}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) == 1 {
+		return []Trivia{nl, {Kind: TriviaLineComment, Raw: []byte("// " + text)}, nl}
+	}
+	for i, l := range lines {
+		lines[i] = strings.ReplaceAll(l, "*/", "* /")
+	}
+	var b strings.Builder
+	if c.indented {
+		pad := c.prefix + strings.Repeat(c.indent, depth)
+		b.WriteString("/*")
+		for _, l := range lines {
+			b.WriteByte('\n')
+			if l != "" {
+				b.WriteString(pad + c.indent + l)
+			}
+		}
+		b.WriteString("\n" + pad + "*/")
+	} else {
+		b.WriteString("/* " + strings.Join(lines, " ") + " */")
+	}
+	return []Trivia{nl, {Kind: TriviaBlockComment, Raw: []byte(b.String())}, nl}
+}
+
+// jsonMarshaler is the json.Marshaler interface.
+type jsonMarshaler interface {
+	MarshalJSON() ([]byte, error)
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*jsonMarshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// isMarshaler reports whether v is encoded by its own MarshalJSON or
+// MarshalText method.
+func isMarshaler(v reflect.Value) bool {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+	}
+	return false
+}
+
+// mapKey returns the name of the member encoding the map key k.
+func mapKey(k reflect.Value) (string, bool) {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), true
+	case reflect.Pointer:
+		if k.IsNil() {
+			return "", false
+		}
+	}
+	if !k.CanInterface() {
+		return "", false // read through an unexported embedded struct
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		b, err := tm.MarshalText()
+		return string(b), err == nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	}
+	return "", false
+}
+
+// field is a struct field encoded as a member.
+type field struct {
+	index   []int
+	comment string
+	tagged  bool
+}
+
+// fieldCache caches the fields of the struct types by member name.
+var fieldCache sync.Map // map[reflect.Type]map[string]field
+
+// cachedFields returns the fields of the struct type t by member name.
+func cachedFields(t reflect.Type) map[string]field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.(map[string]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.(map[string]field)
+}
+
+// typeFields returns the fields of the struct type t by member name,
+// following the rules of json.Marshal for the embedded structs: the fields
+// of an embedded struct without a name in the json tag are promoted, and
+// the shallowest field with a given name wins, or the only tagged one among
+// the shallowest. If none wins, the name is not encoded.
+func typeFields(t reflect.Type) map[string]field {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	fields := make(map[string]field)
+	visited := make(map[reflect.Type]bool)
+	for next := []embedded{{typ: t}}; len(next) > 0; {
+		current := next
+		next = nil
+		found := make(map[string][]field)
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !sf.IsExported() && (!sf.Anonymous || ft.Kind() != reflect.Struct) {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, _, _ := strings.Cut(tag, ",")
+				index := append(e.index[:len(e.index):len(e.index)], i)
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{ft, index})
+					continue
+				}
+				f := field{index: index, tagged: name != ""}
+				if name == "" {
+					name = sf.Name
+				}
+				if text, ok := strings.CutPrefix(sf.Tag.Get("jsonc"), "comment="); ok {
+					f.comment = text
+				}
+				found[name] = append(found[name], f)
+			}
+		}
+		for name, fs := range found {
+			if _, ok := fields[name]; !ok {
+				fields[name] = dominantField(fs)
+			}
+		}
+	}
+	for name, f := range fields {
+		if f.index == nil {
+			delete(fields, name)
+		}
+	}
+	return fields
+}
+
+// dominantField returns the field winning among the fields with the same
+// name and depth, or a field with a nil index if none wins.
+func dominantField(fs []field) field {
+	if len(fs) == 1 {
+		return fs[0]
+	}
+	var win field
+	for _, f := range fs {
+		if f.tagged {
+			if win.index != nil {
+				return field{}
+			}
+			win = f
+		}
+	}
+	return win
+}
@@ -0,0 +1,155 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type marshalConfig struct {
+	marshalEmbedded
+	Name    string            `json:"name" jsonc:"comment=Name of the server"`
+	Port    int               `json:"port,omitempty" jsonc:"comment=Port to listen on"`
+	Hidden  string            `json:"-" jsonc:"comment=Hidden"`
+	TLS     *marshalTLS       `json:"tls" jsonc:"comment=TLS settings\nLeave empty to disable"`
+	Routes  []marshalRoute    `json:"routes"`
+	Backend map[string]any    `json:"backend"`
+	Labels  map[int]marshalIP `json:"labels"`
+	Other   string            `jsonc:"other tag"`
+}
+
+type marshalEmbedded struct {
+	Debug bool   `json:"debug" jsonc:"comment=Enable debug logs"`
+	Name  string `json:"name" jsonc:"comment=Shadowed"`
+}
+
+type marshalTLS struct {
+	Cert string `json:"cert" jsonc:"comment=Path of the certificate */"`
+}
+
+type marshalRoute struct {
+	Path string `json:"path" jsonc:"comment=Route path"`
+}
+
+type marshalIP struct {
+	IP net.IP `json:"ip" jsonc:"comment=IP address"`
+}
+
+var _marshalConfig = marshalConfig{
+	marshalEmbedded: marshalEmbedded{Debug: true, Name: "shadowed"},
+	Name:            "main",
+	Hidden:          "x",
+	TLS:             &marshalTLS{Cert: "cert.pem"},
+	Routes:          []marshalRoute{{Path: "/"}},
+	Backend:         map[string]any{"route": &marshalRoute{Path: "/api"}},
+	Labels:          map[int]marshalIP{1: {IP: net.IPv4(127, 0, 0, 1)}},
+	Other:           "y",
+}
+
+func TestMarshalIndent(t *testing.T) {
+	t.Parallel()
+	b, err := MarshalIndent(_marshalConfig, "", "  ")
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  // Enable debug logs
+  "debug": true,
+  // Name of the server
+  "name": "main",
+  /*
+    TLS settings
+    Leave empty to disable
+  */
+  "tls": {
+    // Path of the certificate */
+    "cert": "cert.pem"
+  },
+  "routes": [
+    {
+      // Route path
+      "path": "/"
+    }
+  ],
+  "backend": {
+    "route": {
+      // Route path
+      "path": "/api"
+    }
+  },
+  "labels": {
+    "1": {
+      // IP address
+      "ip": "127.0.0.1"
+    }
+  },
+  "Other": "y"
+}`, string(b))
+	var v marshalConfig
+	require.NoError(t, Unmarshal(b, &v))
+	want := _marshalConfig
+	want.marshalEmbedded.Name, want.Hidden = "", ""
+	want.Backend = map[string]any{"route": map[string]any{"path": "/api"}}
+	assert.Equal(t, want, v)
+	t.Run("Prefix", func(t *testing.T) {
+		t.Parallel()
+		b, err := MarshalIndent(struct {
+			A *marshalTLS `jsonc:"comment=a\n\nb"`
+		}{&marshalTLS{}}, "> ", "\t")
+		require.NoError(t, err)
+		assert.Equal(t, "{\n> \t/*\n> \t\ta\n\n> \t\tb\n> \t*/\n> \t\"A\": {\n> \t\t// Path of the certificate */\n> \t\t\"cert\": \"\"\n> \t}\n> }", string(b))
+	})
+}
+
+func TestMarshal(t *testing.T) {
+	t.Parallel()
+	b, err := Marshal(_marshalConfig)
+	require.NoError(t, err)
+	assert.Equal(t, `{/* Enable debug logs */"debug":true,/* Name of the server */"name":"main",`+
+		`/* TLS settings Leave empty to disable */"tls":{/* Path of the certificate * / */"cert":"cert.pem"},`+
+		`"routes":[{/* Route path */"path":"/"}],"backend":{"route":{/* Route path */"path":"/api"}},`+
+		`"labels":{"1":{/* IP address */"ip":"127.0.0.1"}},"Other":"y"}`, string(b))
+	var v marshalConfig
+	require.NoError(t, Unmarshal(b, &v))
+	assert.Equal(t, "cert.pem", v.TLS.Cert)
+}
+
+func TestMarshalCompatibility(t *testing.T) {
+	t.Parallel()
+	var m Medium
+	require.NoError(t, Unmarshal(_medium, &m))
+	for _, v := range [...]any{m, &m, nil, []any{1, "a", map[string]int{"b": 2}}, "<html>"} {
+		want, err := json.Marshal(v)
+		require.NoError(t, err)
+		got, err := Marshal(v)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+		want, err = json.MarshalIndent(v, "", "\t")
+		require.NoError(t, err)
+		got, err = MarshalIndent(v, "", "\t")
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	}
+	_, err := Marshal(func() {})
+	assert.Error(t, err)
+	_, err = MarshalIndent(make(chan int), "", "  ")
+	assert.Error(t, err)
+}
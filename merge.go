@@ -0,0 +1,110 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import "bytes"
+
+// MergePatch applies the JSON Merge Patch (RFC 7386) to the JSONC data,
+// returning the patched data: the null members of the patch remove the
+// ones of the data, the objects are merged recursively and any other value
+// replaces the one of the data. The patch can contain comments too.
+//
+// The changes are applied as done by [Modify], so that the comments, the
+// order of the members and the formatting of the data are preserved, except
+// for the values replaced by the patch. The new members are added with the
+// comments of the patch before and after them, and the values written
+// keep the comments inside them. The comments of the patch around its root
+// value are ignored.
+//
+// If the data does not contain any value, the result is the patch without
+// the null members, formatted as done by [Format].
+//
+// It returns the errors reported by [Parse] for the data or the patch.
+func MergePatch(data, patch []byte) ([]byte, error) {
+	pdoc, err := Parse(patch)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		f := formatter{indent: "  "}
+		f.value(mergeNode(pdoc.Value))
+		return append(f.buf, '\n'), nil
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if pdoc.Value.Kind != KindObject || doc.Value.Kind != KindObject {
+		return patchEdit(data, doc, nil, nodeValue{node: mergeNode(pdoc.Value)}, ModifyOptions{})
+	}
+	return mergeObject(data, nil, pdoc.Value)
+}
+
+// mergeObject merges the object patch into the object of data at the given
+// path.
+func mergeObject(data []byte, path []any, patch *Node) ([]byte, error) {
+	for _, pm := range patch.Members {
+		doc, err := Parse(data)
+		if err != nil {
+			return nil, err
+		}
+		n := doc.Value
+		for _, key := range path {
+			n, _ = child(n, key)
+		}
+		p := append(path[:len(path):len(path)], pm.Name())
+		target, _ := child(n, pm.Name())
+		switch {
+		case pm.Value.Kind == KindNull:
+			if target != nil {
+				data, err = patchEdit(data, doc, p, Remove, ModifyOptions{})
+			}
+		case target == nil:
+			trailing := append(append([]Trivia(nil), pm.BeforeComma...), pm.Trailing...)
+			v := nodeValue{node: mergeNode(pm.Value), leading: pm.Key.Leading, trailing: trailing}
+			data, err = patchEdit(data, doc, p, v, ModifyOptions{})
+		case pm.Value.Kind == KindObject && target.Kind == KindObject:
+			data, err = mergeObject(data, p, pm.Value)
+		default:
+			data, err = patchEdit(data, doc, p, nodeValue{node: mergeNode(pm.Value)}, ModifyOptions{})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// mergeNode returns the result of merging n into a missing value: n itself,
+// or a copy of it without the null members if it is an object.
+func mergeNode(n *Node) *Node {
+	if n.Kind != KindObject {
+		return n
+	}
+	c := *n
+	c.Members = make([]*Member, 0, len(n.Members))
+	for _, m := range n.Members {
+		if m.Value.Kind == KindNull {
+			continue
+		}
+		mc := *m
+		mc.Value = mergeNode(m.Value)
+		c.Members = append(c.Members, &mc)
+	}
+	for i, m := range c.Members {
+		m.Comma = i < len(c.Members)-1
+	}
+	return &c
+}
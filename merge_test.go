@@ -0,0 +1,106 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name  string
+		Data  string
+		Patch string
+		Want  string
+	}{
+		{"Replace", "{\n  // a\n  \"a\": 1, // one\n  \"b\": 2\n}", `{"a": "x"}`, "{\n  // a\n  \"a\": \"x\", // one\n  \"b\": 2\n}"},
+		{"Remove", "{\n  \"a\": 1,\n  // b\n  \"b\": 2,\n  \"c\": 3 // c\n}", `{"b": null}`, "{\n  \"a\": 1,\n  \"c\": 3 // c\n}"},
+		{"RemoveLast", "{\"a\": 1, // a\n \"b\": 2}", `{"b": null}`, "{\"a\": 1 // a\n}"},
+		{"RemoveMissing", `{"a": 1}`, `{"b": null}`, `{"a": 1}`},
+		{"Add", "{\n  \"a\": 1\n}", "{\n  // b\n  \"b\": 2 // two\n}", "{\n  \"a\": 1,\n  // b\n  \"b\": 2 // two\n}"},
+		{"AddAfterLineComment", "{\"a\": 1 // c\n}", `{"b": 2}`, "{\"a\": 1, // c\n  \"b\": 2\n}"},
+		{"AddComma", "{\n  \"a\": 1,\n}", "{\n  /* b */\n  \"b\": 2, // two\n  \"c\": 3\n}", "{\n  \"a\": 1,\n  /* b */\n  \"b\": 2, // two\n  \"c\": 3,\n}"},
+		{"AddBeforeBrace", "{\n  \"a\": 1}", "{\"b\": 2 // two\n}", "{\n  \"a\": 1,\n  \"b\": 2 /* two */}"},
+		{"AddSingleLine", `{"a": 1}`, "{\n  // b\n  \"b\": [1, // one\n  2]\n}", `{"a": 1, /* b */ "b": [1,/* one */2]}`},
+		{"AddObject", "{\n  \"a\": 1\n}", `{"b": {"c": /* c */ 1, "d": null, "e": {"f": null}}}`, "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": /* c */ 1,\n    \"e\": {}\n  }\n}"},
+		{"AddEmpty", "{\n  // c\n}", `{"a": 1}`, "{\n  \"a\": 1\n  // c\n}"},
+		{"Nested", "{\n  \"a\": {\n    // b\n    \"b\": 1,\n    \"c\": 2\n  }\n}", `{"a": {"b": 3, "c": null, "d": true}}`, "{\n  \"a\": {\n    // b\n    \"b\": 3,\n    \"d\": true\n  }\n}"},
+		{"ReplaceObject", "{\n  \"a\": [1] // a\n}", `{"a": {"b": 1, "c": null}}`, "{\n  \"a\": {\n    \"b\": 1\n  } // a\n}"},
+		{"ReplaceArray", `{"a": [1, 2]}`, `{"a": [3]}`, `{"a": [3]}`},
+		{"ReplaceComments", "{\n  \"a\": 1\n}", `{"a": [1, /* c */ 2]}`, "{\n  \"a\": [\n    1, /* c */\n    2\n  ]\n}"},
+		{"RootNotObject", "// c\n[1]", `{"a": 1, "b": null}`, "// c\n{\"a\":1}"},
+		{"PatchNotObject", "// c\n{\"a\": 1}", `[1]`, "// c\n[1]"},
+		{"PatchNull", `{"a": 1}`, `null`, `null`},
+		{"EmptyData", "", "// x\n{\"a\": {\"b\": 1, \"c\": null}}", "{\n  \"a\": {\n    \"b\": 1\n  }\n}\n"},
+		{"EmptyPatch", "{\n  \"a\": 1 // a\n}", `{}`, "{\n  \"a\": 1 // a\n}"},
+		{"CRLF", "{\r\n  \"a\": 1\r\n}", "{\"b\": {\n  // c\n  \"c\": 1}}", "{\r\n  \"a\": 1,\r\n  \"b\": {\r\n    // c\r\n    \"c\": 1\r\n  }\r\n}"},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			got, err := MergePatch([]byte(tt.Data), []byte(tt.Patch))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(got))
+			_, err = Parse(got)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// TestMergePatchRFC checks the examples of the appendix A of RFC 7386.
+func TestMergePatchRFC(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Data  string
+		Patch string
+		Want  string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	} {
+		got, err := MergePatch([]byte(tt.Data), []byte(tt.Patch))
+		require.NoError(t, err)
+		assert.JSONEq(t, tt.Want, string(got), "%s + %s", tt.Data, tt.Patch)
+	}
+}
+
+func TestMergePatchError(t *testing.T) {
+	t.Parallel()
+	var serr *SyntaxError
+	_, err := MergePatch([]byte(`{"a": }`), []byte(`{}`))
+	assert.ErrorAs(t, err, &serr)
+	_, err = MergePatch([]byte(`{}`), []byte(`{`))
+	assert.ErrorAs(t, err, &serr)
+}
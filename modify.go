@@ -0,0 +1,650 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/marcozac/go-jsonc/internal/json"
+)
+
+// ErrInvalidPath is returned by [Modify] if the path cannot be applied to
+// the data, as an array index used on an object.
+var ErrInvalidPath = errors.New("jsonc: invalid path")
+
+// Remove is the value to pass to [Modify] to remove the value at the given
+// path.
+var Remove any = removeValue{}
+
+type removeValue struct{}
+
+// nodeValue is a value of a concrete syntax tree, written by the modifier
+// with its comments instead of being marshaled. If it is inserted as a new
+// member or element, the leading and the trailing comments are written
+// around it.
+type nodeValue struct {
+	node              *Node
+	leading, trailing []Trivia
+}
+
+// Edit is a change to JSONC data: it replaces the bytes in Range with
+// Content.
+type Edit struct {
+	Range   Range
+	Content string
+}
+
+// ModifyOptions configures [Modify].
+type ModifyOptions struct {
+	// Indent is the string used for one level of indentation of the new
+	// members and elements. If empty, it is inferred from the data.
+	Indent string
+
+	// Insert inserts the value in the array at the index given by the last
+	// element of the path, shifting the following elements, instead of
+	// replacing the element at that index.
+	Insert bool
+}
+
+// Modify returns the edits that set the value at the given path of the
+// JSONC data, preserving the comments and the formatting of the rest of the
+// data. The edits can be applied with [ApplyEdits].
+//
+// The path is a list of object keys (string) and array indices (int), as
+// []any{"editor", "rulers", 0}. The value is marshaled as done by
+// json.Marshal, or removed if it is [Remove]. Setting an index equal to the
+// length of an array appends the value to it, and the missing objects in
+// the path are created.
+//
+// The new members and elements follow the style of their container: they
+// are placed on their own line, with the inferred indentation and new line
+// sequence, in multi-line containers and are separated by a space in
+// single-line ones. A trailing comma is added if the last member or element
+// of the container has one.
+//
+// It returns the errors reported by [Parse], [ErrInvalidPath] if the path
+// cannot be applied to the data, as an index out of range, and the errors
+// reported by json.Marshal. Removing a missing value is not an error and
+// returns no edits.
+func Modify(data []byte, path []any, value any, opts ModifyOptions) ([]Edit, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return modifyEmpty(data, path, value)
+	}
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return modifyDocument(data, doc, path, value, opts)
+}
+
+// modifyDocument is like [Modify], but uses doc, the concrete syntax tree of
+// data.
+func modifyDocument(data []byte, doc *Document, path []any, value any, opts ModifyOptions) ([]Edit, error) {
+	m := modifier{data: data, indent: opts.Indent, insertAt: opts.Insert}
+	m.init(doc)
+	return m.modify(doc.Value, path, value)
+}
+
+// ApplyEdits returns a copy of data with the given edits applied. The edits
+// must not overlap, as the ones returned by [Modify], and are applied in
+// the order of their offsets.
+func ApplyEdits(data []byte, edits []Edit) ([]byte, error) {
+	edits = append([]Edit(nil), edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].Range.Start < edits[j].Range.Start
+	})
+	out := make([]byte, 0, len(data))
+	last := 0
+	for _, e := range edits {
+		if e.Range.Start < last || e.Range.End < e.Range.Start || e.Range.End > len(data) {
+			return nil, fmt.Errorf("jsonc: invalid edit range [%d, %d)", e.Range.Start, e.Range.End)
+		}
+		out = append(out, data[last:e.Range.Start]...)
+		out = append(out, e.Content...)
+		last = e.Range.End
+	}
+	return append(out, data[last:]...), nil
+}
+
+// modifyEmpty returns the edit replacing data, which does not contain any
+// value, with the value at the given path.
+func modifyEmpty(data []byte, path []any, value any) ([]Edit, error) {
+	if value == Remove {
+		return nil, nil
+	}
+	value, err := nest(path, value)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []Edit{{Range{0, len(data)}, string(b) + "\n"}}, nil
+}
+
+// modifier computes the edits of [Modify].
+type modifier struct {
+	data      []byte
+	indent    string // one level of indentation
+	eol       string // new line sequence
+	multiline bool   // the root value spans multiple lines
+	insertAt  bool   // insert into arrays instead of replacing
+}
+
+// init infers the formatting of the data, unless given by the options.
+func (m *modifier) init(doc *Document) {
+	m.eol = "\n"
+	if bytes.Contains(m.data, []byte("\r\n")) {
+		m.eol = "\r\n"
+	}
+	root := doc.Value.Range
+	m.multiline = bytes.IndexByte(m.data[root.Start:root.End], '\n') >= 0
+	if m.indent == "" {
+		m.indent = m.inferIndent(doc.Value)
+	}
+	if m.indent == "" {
+		m.indent = "  "
+		if bytes.Contains(m.data, []byte("\n\t")) {
+			m.indent = "\t"
+		}
+	}
+}
+
+// inferIndent returns the indentation of the first child of a multi-line
+// container relative to the one of the container, or an empty string if
+// there are no multi-line containers.
+func (m *modifier) inferIndent(n *Node) string {
+	its := items(n)
+	if len(its) == 0 {
+		return ""
+	}
+	if m.isMultiline(n) {
+		outer, inner := m.lineIndent(n.Range.Start), m.lineIndent(its[0].valueStart)
+		if len(inner) > len(outer) && strings.HasPrefix(inner, outer) {
+			return inner[len(outer):]
+		}
+	}
+	for _, it := range its {
+		if indent := m.inferIndent(it.value); indent != "" {
+			return indent
+		}
+	}
+	return ""
+}
+
+// modify returns the edits setting value at path, relative to n.
+func (m *modifier) modify(n *Node, path []any, value any) ([]Edit, error) {
+	if len(path) == 0 {
+		if value == Remove {
+			return nil, fmt.Errorf("%w: cannot remove the root value", ErrInvalidPath)
+		}
+		return m.replace(n, value, m.multiline)
+	}
+	for i, key := range path[:len(path)-1] {
+		child, err := child(n, key)
+		if err != nil {
+			return nil, err
+		}
+		if child == nil {
+			if value == Remove {
+				return nil, nil
+			}
+			nested, err := nest(path[i+1:], value)
+			if err != nil {
+				return nil, err
+			}
+			return m.set(n, key, nested)
+		}
+		n = child
+	}
+	key := path[len(path)-1]
+	if value == Remove {
+		return m.remove(n, key)
+	}
+	return m.set(n, key, value)
+}
+
+// set returns the edits setting the value of the given key or index of n.
+func (m *modifier) set(n *Node, key, value any) ([]Edit, error) {
+	switch key := key.(type) {
+	case string:
+		if n.Kind != KindObject {
+			return nil, fmt.Errorf("%w: cannot set key %q of %s", ErrInvalidPath, key, n.Kind)
+		}
+		if i := memberIndex(n, key); i >= 0 {
+			return m.replace(n.Members[i].Value, value, m.isMultiline(n))
+		}
+		name, _ := json.Marshal(key)
+		multiline := m.isMultiline(n) || len(n.Members) == 0 && m.multiline
+		return m.insert(n, len(n.Members), func(indent string) (string, error) {
+			b, err := m.marshal(value, indent, multiline)
+			return m.leadingComments(value, indent, multiline) + string(name) + ": " + b, err
+		}, trailingTrivia(value))
+	case int:
+		if n.Kind != KindArray {
+			return nil, fmt.Errorf("%w: cannot set index %d of %s", ErrInvalidPath, key, n.Kind)
+		}
+		if key < 0 || key > len(n.Elements) {
+			return nil, fmt.Errorf("%w: index %d out of range", ErrInvalidPath, key)
+		}
+		if key < len(n.Elements) && !m.insertAt {
+			return m.replace(n.Elements[key].Value, value, m.isMultiline(n))
+		}
+		multiline := m.isMultiline(n) || len(n.Elements) == 0 && m.multiline
+		return m.insert(n, key, func(indent string) (string, error) {
+			b, err := m.marshal(value, indent, multiline)
+			return m.leadingComments(value, indent, multiline) + b, err
+		}, trailingTrivia(value))
+	}
+	return nil, fmt.Errorf("%w: invalid path element %v (%T)", ErrInvalidPath, key, key)
+}
+
+// replace returns the edit replacing n with value.
+func (m *modifier) replace(n *Node, value any, multiline bool) ([]Edit, error) {
+	b, err := m.marshal(value, m.lineIndent(n.Range.Start), multiline)
+	if err != nil {
+		return nil, err
+	}
+	return []Edit{{n.Range, b}}, nil
+}
+
+// insert returns the edits inserting a member or an element, whose text is
+// returned by text given its indentation, at the index i of the container
+// n. The comments in trailing are written after it, on the same line.
+func (m *modifier) insert(n *Node, i int, text func(indent string) (string, error), trailing []Trivia) ([]Edit, error) {
+	its := items(n)
+	if len(its) == 0 {
+		return m.insertEmpty(n, text, trailing)
+	}
+	multiline := m.isMultiline(n)
+	last := its[len(its)-1]
+	sep, indent := " ", ""
+	switch {
+	case multiline:
+		indent = m.lineIndent(its[0].valueStart)
+		sep = m.eol + indent
+	case i == len(its) && last.lineComment:
+		// the last item ends with a line comment: move to a new line, so
+		// that the new one is not commented out
+		indent = m.lineIndent(n.Range.Start) + m.indent
+		sep = m.eol + indent
+	}
+	t, err := text(indent)
+	if err != nil {
+		return nil, err
+	}
+	if i < len(its) {
+		// before the i-th item, after its leading trivia if single-line
+		if multiline {
+			start := its[i].start
+			return []Edit{{Range{start, start}, sep + t + "," + m.trailingComments(trailing, m.lineEnd(start))}}, nil
+		}
+		return []Edit{{Range{its[i].valueStart, its[i].valueStart}, t + "," + m.trailingComments(trailing, false) + sep}}, nil
+	}
+	if last.comma >= 0 {
+		// keep the trailing comma style
+		return []Edit{{Range{last.end, last.end}, sep + t + "," + m.trailingComments(trailing, m.lineEnd(last.end))}}, nil
+	}
+	comma := last.valueEnd
+	if c := last.beforeComma; c >= 0 {
+		comma = c
+	}
+	t += m.trailingComments(trailing, m.lineEnd(last.end))
+	if comma == last.end {
+		return []Edit{{Range{comma, comma}, "," + sep + t}}, nil
+	}
+	return []Edit{
+		{Range{comma, comma}, ","},
+		{Range{last.end, last.end}, sep + t},
+	}, nil
+}
+
+// insertEmpty returns the edits inserting the first member or element of
+// the empty container n.
+func (m *modifier) insertEmpty(n *Node, text func(indent string) (string, error), trailing []Trivia) ([]Edit, error) {
+	open, end := n.Range.Start+1, n.Range.End-1
+	outer := m.lineIndent(n.Range.Start)
+	indent := ""
+	if m.multiline {
+		indent = outer + m.indent
+	}
+	t, err := text(indent)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case triviaComments(n.End):
+		if m.multiline {
+			return []Edit{{Range{open, open}, m.eol + indent + t + m.trailingComments(trailing, m.lineEnd(open))}}, nil
+		}
+		return []Edit{{Range{open, open}, t + m.trailingComments(trailing, false) + " "}}, nil
+	case m.multiline:
+		return []Edit{{Range{open, end}, m.eol + indent + t + m.trailingComments(trailing, true) + m.eol + outer}}, nil
+	}
+	return []Edit{{Range{open, end}, t + m.trailingComments(trailing, false)}}, nil
+}
+
+// remove returns the edits removing the given key or index of n.
+func (m *modifier) remove(n *Node, key any) ([]Edit, error) {
+	i := -1
+	switch key := key.(type) {
+	case string:
+		if n.Kind != KindObject {
+			return nil, fmt.Errorf("%w: cannot remove key %q of %s", ErrInvalidPath, key, n.Kind)
+		}
+		i = memberIndex(n, key)
+	case int:
+		if n.Kind != KindArray {
+			return nil, fmt.Errorf("%w: cannot remove index %d of %s", ErrInvalidPath, key, n.Kind)
+		}
+		if key >= 0 && key < len(n.Elements) {
+			i = key
+		}
+	default:
+		return nil, fmt.Errorf("%w: invalid path element %v (%T)", ErrInvalidPath, key, key)
+	}
+	if i < 0 {
+		return nil, nil
+	}
+	its := items(n)
+	it := its[i]
+	switch {
+	case len(its) == 1:
+		end := it.end
+		if !triviaComments(n.End) {
+			end = n.Range.End - 1
+		}
+		return []Edit{{Range{it.start, end}, ""}}, nil
+	case i < len(its)-1:
+		next := its[i+1]
+		if next.start < next.valueStart && !hasNewLine(m.data[next.start:next.valueStart]) &&
+			!triviaComments(next.leading) {
+			// single-line: keep the leading white spaces of the item
+			return []Edit{{Range{it.valueStart, next.valueStart}, ""}}, nil
+		}
+		return []Edit{{Range{it.start, next.start}, ""}}, nil
+	case it.comma >= 0:
+		// last item with a trailing comma: keep the one of the previous item
+		return []Edit{m.removeLast(n, its[i-1], it)}, nil
+	}
+	prev := its[i-1]
+	return []Edit{
+		{Range{prev.comma, prev.comma + 1}, ""},
+		m.removeLast(n, prev, it),
+	}, nil
+}
+
+// removeLast returns the edit removing it, the last member or element of
+// n, leaving the trailing comments of the previous one prev untouched. If
+// they end with a line comment, the closing bracket is kept on a new line.
+func (m *modifier) removeLast(n *Node, prev, it item) Edit {
+	if prev.lineComment && !m.lineEnd(it.end) {
+		return Edit{Range{it.start, it.end}, m.eol + m.lineIndent(n.Range.Start)}
+	}
+	return Edit{Range{it.start, it.end}, ""}
+}
+
+// marshal returns the JSON encoding of value. If multiline is true, it is
+// indented with the given indentation of its first line.
+func (m *modifier) marshal(value any, indent string, multiline bool) (string, error) {
+	if v, ok := value.(nodeValue); ok {
+		return m.format(v.node, indent, multiline), nil
+	}
+	var b []byte
+	var err error
+	if multiline {
+		// the prefix is added here, since jsoniter does not support it
+		b, err = json.MarshalIndent(value, "", m.indent)
+		b = bytes.ReplaceAll(b, []byte("\n"), []byte(m.eol+indent))
+	} else {
+		b, err = json.Marshal(value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("jsonc: %w", err)
+	}
+	return string(b), nil
+}
+
+// format returns the text of n with its comments, formatted as done by
+// marshal.
+func (m *modifier) format(n *Node, indent string, multiline bool) string {
+	if !multiline {
+		c := compacter{keepComments: true}
+		c.node(n)
+		return string(c.buf)
+	}
+	f := formatter{indent: m.indent}
+	f.value(n)
+	b := bytes.ReplaceAll(f.buf, []byte("\r\n"), []byte("\n"))
+	return string(bytes.ReplaceAll(b, []byte("\n"), []byte(m.eol+indent)))
+}
+
+// leadingComments returns the leading comments of value, if it is a
+// [nodeValue], to write before it: each one on its own line if multiline is
+// true, or as block comments on the same line otherwise.
+func (m *modifier) leadingComments(value any, indent string, multiline bool) string {
+	v, ok := value.(nodeValue)
+	if !ok {
+		return ""
+	}
+	var b []byte
+	for _, t := range v.leading {
+		switch {
+		case t.Kind == TriviaWhitespace:
+			continue
+		case multiline:
+			b = append(b, bytes.TrimRight(t.Raw, " \t\r")...)
+			b = append(b, m.eol+indent...)
+		default:
+			c := compacter{buf: b, keepComments: true}
+			c.trivia([]Trivia{t})
+			b = append(c.buf, ' ')
+		}
+	}
+	return string(b)
+}
+
+// trailingComments returns the comments in t to write after a member or an
+// element on the same line. Line comments are converted to block comments,
+// unless eol reports that the last one is followed by a new line.
+func (m *modifier) trailingComments(t []Trivia, eol bool) string {
+	var b []byte
+	for i, tr := range t {
+		switch {
+		case tr.Kind == TriviaWhitespace:
+			continue
+		case tr.Kind == TriviaLineComment && eol && !triviaComments(t[i+1:]):
+			b = append(b, ' ')
+			b = append(b, bytes.TrimRight(tr.Raw, " \t\r")...)
+		default:
+			c := compacter{buf: append(b, ' '), keepComments: true}
+			c.trivia([]Trivia{tr})
+			b = c.buf
+		}
+	}
+	return string(b)
+}
+
+// lineEnd reports whether the given offset is followed only by white spaces
+// on its line.
+func (m *modifier) lineEnd(off int) bool {
+	for _, c := range m.data[off:] {
+		switch c {
+		case ' ', '\t':
+			continue
+		case '\r', '\n':
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// isMultiline reports whether the members or the elements of the container
+// n are on their own lines.
+func (m *modifier) isMultiline(n *Node) bool {
+	its := items(n)
+	if len(its) == 0 {
+		return hasNewLine(appendTrivia(nil, n.End))
+	}
+	return hasNewLine(m.data[n.Range.Start:its[0].valueStart])
+}
+
+// lineIndent returns the white spaces at the beginning of the line
+// including the given offset.
+func (m *modifier) lineIndent(off int) string {
+	start := bytes.LastIndexByte(m.data[:off], '\n') + 1
+	end := start
+	for end < off && (m.data[end] == ' ' || m.data[end] == '\t') {
+		end++
+	}
+	return string(m.data[start:end])
+}
+
+// trailingTrivia returns the trailing comments of value, if it is a
+// [nodeValue].
+func trailingTrivia(value any) []Trivia {
+	if v, ok := value.(nodeValue); ok {
+		return v.trailing
+	}
+	return nil
+}
+
+// item is a member or an element of a container, with the offsets needed to
+// edit it.
+type item struct {
+	value       *Node    // the value
+	leading     []Trivia // the leading trivia of the key or the value
+	start       int      // start of the leading trivia
+	valueStart  int      // start of the key or the value
+	valueEnd    int      // end of the value
+	beforeComma int      // end of the trivia before the comma, or -1
+	comma       int      // offset of the comma, or -1
+	end         int      // end of the trailing trivia, comma or value
+	lineComment bool     // the trailing trivia end with a line comment
+}
+
+// items returns the members or the elements of the container n.
+func items(n *Node) []item {
+	its := make([]item, 0, len(n.Members)+len(n.Elements))
+	for _, mb := range n.Members {
+		its = append(its, newItem(mb.Key, mb.Value, mb.BeforeComma, mb.Comma, mb.Trailing))
+	}
+	for _, e := range n.Elements {
+		its = append(its, newItem(e.Value, e.Value, e.BeforeComma, e.Comma, e.Trailing))
+	}
+	return its
+}
+
+func newItem(first, value *Node, beforeComma []Trivia, comma bool, trailing []Trivia) item {
+	it := item{
+		value:       value,
+		leading:     first.Leading,
+		start:       first.Range.Start,
+		valueStart:  first.Range.Start,
+		valueEnd:    value.Range.End,
+		beforeComma: -1,
+		comma:       -1,
+		end:         value.Range.End,
+	}
+	if len(first.Leading) > 0 {
+		it.start = first.Leading[0].Range.Start
+	}
+	if len(beforeComma) > 0 {
+		it.beforeComma = beforeComma[len(beforeComma)-1].Range.End
+		it.end = it.beforeComma
+	}
+	if comma {
+		it.comma = it.end
+		it.end++
+	}
+	if len(trailing) > 0 {
+		it.end = trailing[len(trailing)-1].Range.End
+	}
+	for i := len(trailing) - 1; i >= 0; i-- {
+		if trailing[i].Kind != TriviaWhitespace {
+			it.lineComment = trailing[i].Kind == TriviaLineComment
+			break
+		}
+	}
+	return it
+}
+
+// child returns the value of the given key or index of n, or nil if it does
+// not exist.
+func child(n *Node, key any) (*Node, error) {
+	switch key := key.(type) {
+	case string:
+		if n.Kind != KindObject {
+			return nil, fmt.Errorf("%w: cannot get key %q of %s", ErrInvalidPath, key, n.Kind)
+		}
+		if i := memberIndex(n, key); i >= 0 {
+			return n.Members[i].Value, nil
+		}
+		return nil, nil
+	case int:
+		if n.Kind != KindArray {
+			return nil, fmt.Errorf("%w: cannot get index %d of %s", ErrInvalidPath, key, n.Kind)
+		}
+		if key < 0 || key >= len(n.Elements) {
+			return nil, fmt.Errorf("%w: index %d out of range", ErrInvalidPath, key)
+		}
+		return n.Elements[key].Value, nil
+	}
+	return nil, fmt.Errorf("%w: invalid path element %v (%T)", ErrInvalidPath, key, key)
+}
+
+// memberIndex returns the index of the last member of the object n with the
+// given name, as the one used by json.Unmarshal, or -1.
+func memberIndex(n *Node, name string) int {
+	for i := len(n.Members) - 1; i >= 0; i-- {
+		if n.Members[i].Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// nest returns value nested in the objects with the keys of the given path.
+func nest(path []any, value any) (any, error) {
+	for i := len(path) - 1; i >= 0; i-- {
+		key, ok := path[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot create index %v", ErrInvalidPath, path[i])
+		}
+		value = map[string]any{key: value}
+	}
+	return value, nil
+}
+
+// triviaComments reports whether t includes any comment.
+func triviaComments(t []Trivia) bool {
+	for _, tr := range t {
+		if tr.Kind != TriviaWhitespace {
+			return true
+		}
+	}
+	return false
+}
+
+// hasNewLine reports whether b contains a new line.
+func hasNewLine(b []byte) bool {
+	return bytes.IndexByte(b, '\n') >= 0
+}
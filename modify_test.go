@@ -0,0 +1,132 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModify(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name  string
+		Data  string
+		Path  []any
+		Value any
+		Opts  ModifyOptions
+		Want  string
+	}{
+		{"Replace", "{\n  // c\n  \"a\": 1 // d\n}", []any{"a"}, 2, ModifyOptions{}, "{\n  // c\n  \"a\": 2 // d\n}"},
+		{"ReplaceMultiline", "{\n\t\"a\": 1\n}", []any{"a"}, []int{1, 2}, ModifyOptions{}, "{\n\t\"a\": [\n\t\t1,\n\t\t2\n\t]\n}"},
+		{"ReplaceRoot", "// c\n1", nil, true, ModifyOptions{}, "// c\ntrue"},
+		{"ReplaceLastDuplicate", `{"a": 1, "a": 2}`, []any{"a"}, 3, ModifyOptions{}, `{"a": 1, "a": 3}`},
+		{"InsertMultiline", "{\n  \"a\": 1 // c\n}", []any{"b"}, 2, ModifyOptions{}, "{\n  \"a\": 1, // c\n  \"b\": 2\n}"},
+		{"InsertSingleLine", `{"a": 1}`, []any{"b"}, "x", ModifyOptions{}, `{"a": 1, "b": "x"}`},
+		{"InsertTrailingComma", "{\n  \"a\": 1,\n}", []any{"b"}, 2, ModifyOptions{}, "{\n  \"a\": 1,\n  \"b\": 2,\n}"},
+		{"InsertTrailingComment", "{\n  \"a\": 1 /* c */\n}", []any{"b"}, 2, ModifyOptions{}, "{\n  \"a\": 1, /* c */\n  \"b\": 2\n}"},
+		{"InsertAfterLineComment", "{\"a\": 1 // c\n}", []any{"b"}, 2, ModifyOptions{}, "{\"a\": 1, // c\n  \"b\": 2\n}"},
+		{"InsertAfterLineCommentComma", "{\"a\": 1, // c\n}", []any{"b"}, 2, ModifyOptions{}, "{\"a\": 1, // c\n  \"b\": 2,\n}"},
+		{"InsertEmpty", "{\n  \"a\": {}\n}", []any{"a", "b"}, 1, ModifyOptions{}, "{\n  \"a\": {\n    \"b\": 1\n  }\n}"},
+		{"InsertEmptyComment", "{\n  \"a\": { // c\n  }\n}", []any{"a", "b"}, 1, ModifyOptions{}, "{\n  \"a\": {\n    \"b\": 1 // c\n  }\n}"},
+		{"InsertEmptyCompact", `{}`, []any{"a"}, 1, ModifyOptions{}, `{"a": 1}`},
+		{"InsertNested", "{\n  \"a\": 1\n}", []any{"b", "c"}, 2, ModifyOptions{}, "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": 2\n  }\n}"},
+		{"InsertIndentOption", "{\n  \"a\": 1\n}", []any{"b"}, []int{1}, ModifyOptions{Indent: "\t"}, "{\n  \"a\": 1,\n  \"b\": [\n  \t1\n  ]\n}"},
+		{"InsertCRLF", "{\r\n  \"a\": 1\r\n}", []any{"b"}, []int{1}, ModifyOptions{}, "{\r\n  \"a\": 1,\r\n  \"b\": [\r\n    1\r\n  ]\r\n}"},
+		{"ArrayReplace", `[1, 2, 3]`, []any{1}, 5, ModifyOptions{}, `[1, 5, 3]`},
+		{"ArrayAppend", "[\n  1 // c\n]", []any{1}, 2, ModifyOptions{}, "[\n  1, // c\n  2\n]"},
+		{"ArrayAppendAfterLineComment", "[1 // c\n]", []any{1}, 2, ModifyOptions{}, "[1, // c\n  2\n]"},
+		{"ArrayInsert", `[1, 2]`, []any{0}, 0, ModifyOptions{Insert: true}, `[0, 1, 2]`},
+		{"ArrayInsertMultiline", "[\n  // c\n  1\n]", []any{0}, 0, ModifyOptions{Insert: true}, "[\n  0,\n  // c\n  1\n]"},
+		{"RemoveFirst", "{\n  // a\n  \"a\": 1,\n  \"b\": 2\n}", []any{"a"}, Remove, ModifyOptions{}, "{\n  \"b\": 2\n}"},
+		{"RemoveMiddle", `{"a": 1, "b": 2, "c": 3}`, []any{"b"}, Remove, ModifyOptions{}, `{"a": 1, "c": 3}`},
+		{"RemoveLast", "{\n  \"a\": 1,\n  \"b\": 2 // b\n}", []any{"b"}, Remove, ModifyOptions{}, "{\n  \"a\": 1\n}"},
+		{"RemoveLastPreviousComment", "{\n  \"a\": 1, // a\n  \"b\": 2\n}", []any{"b"}, Remove, ModifyOptions{}, "{\n  \"a\": 1 // a\n}"},
+		{"RemoveLastPreviousCommentBracket", "{\"a\": 1, // a\n \"b\": 2}", []any{"b"}, Remove, ModifyOptions{}, "{\"a\": 1 // a\n}"},
+		{"RemoveLastPreviousBlockComment", `[1, /* a */ 2]`, []any{1}, Remove, ModifyOptions{}, `[1 /* a */ ]`},
+		{"RemoveLastTrailingComma", "{\n  \"a\": 1,\n  \"b\": 2,\n}", []any{"b"}, Remove, ModifyOptions{}, "{\n  \"a\": 1,\n}"},
+		{"RemoveOnly", "{\n  \"a\": 1\n}", []any{"a"}, Remove, ModifyOptions{}, "{}"},
+		{"RemoveOnlyComment", "{\n  \"a\": 1\n  // c\n}", []any{"a"}, Remove, ModifyOptions{}, "{\n  // c\n}"},
+		{"RemoveIndex", `[1, 2, 3]`, []any{2}, Remove, ModifyOptions{}, `[1, 2]`},
+		{"RemoveMissing", `{"a": 1}`, []any{"b", "c"}, Remove, ModifyOptions{}, `{"a": 1}`},
+		{"Empty", "", []any{"a"}, 1, ModifyOptions{}, "{\n  \"a\": 1\n}\n"},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			edits, err := Modify([]byte(tt.Data), tt.Path, tt.Value, tt.Opts)
+			require.NoError(t, err)
+			got, err := ApplyEdits([]byte(tt.Data), edits)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(got))
+			_, err = Parse(got)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestModifyError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name  string
+		Data  string
+		Path  []any
+		Value any
+	}{
+		{"KeyOfArray", `[1]`, []any{"a"}, 1},
+		{"IndexOfObject", `{"a": 1}`, []any{0}, 1},
+		{"IndexOutOfRange", `[1]`, []any{2}, 1},
+		{"TraverseScalar", `{"a": 1}`, []any{"a", "b"}, 1},
+		{"CreateIndex", `{}`, []any{"a", 0}, 1},
+		{"InvalidElement", `{}`, []any{1.5}, 1},
+		{"RemoveRoot", `{}`, nil, Remove},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Modify([]byte(tt.Data), tt.Path, tt.Value, ModifyOptions{})
+			assert.ErrorIs(t, err, ErrInvalidPath)
+		})
+	}
+	t.Run("Syntax", func(t *testing.T) {
+		t.Parallel()
+		_, err := Modify([]byte(`{"a": }`), []any{"a"}, 1, ModifyOptions{})
+		var serr *SyntaxError
+		assert.ErrorAs(t, err, &serr)
+	})
+	t.Run("Marshal", func(t *testing.T) {
+		t.Parallel()
+		_, err := Modify([]byte(`{}`), []any{"a"}, func() {}, ModifyOptions{})
+		assert.Error(t, err)
+	})
+}
+
+func TestApplyEdits(t *testing.T) {
+	t.Parallel()
+	data := []byte(`abcdef`)
+	got, err := ApplyEdits(data, []Edit{{Range{4, 5}, "E"}, {Range{0, 0}, "_"}, {Range{1, 3}, ""}})
+	require.NoError(t, err)
+	assert.Equal(t, "_adEf", string(got))
+	assert.Equal(t, "abcdef", string(data))
+	_, err = ApplyEdits(data, []Edit{{Range{0, 3}, ""}, {Range{2, 4}, ""}})
+	assert.Error(t, err, "overlapping")
+	_, err = ApplyEdits(data, []Edit{{Range{5, 7}, ""}})
+	assert.Error(t, err, "out of range")
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+// Options configures the dialect and the behavior of [Options.Sanitize] and
+// [Options.Unmarshal]. The zero value behaves as [Sanitize] and [Unmarshal].
+//
+// An Options value can be configured once and reused by multiple goroutines,
+// so that different call sites in the same program can use different
+// settings:
+//
+//	var settings = jsonc.Options{TrailingCommas: true, Strict: true}
+//
+//	func load(data []byte, v any) error {
+//		return settings.Unmarshal(data, v)
+//	}
+type Options struct {
+	// TrailingCommas removes the trailing commas from objects and arrays, as
+	// done by [SanitizeJWCC].
+	TrailingCommas bool
+
+	// JSON5 converts JSON5 data to JSON, as done by [SanitizeJSON5]. It
+	// implies TrailingCommas.
+	JSON5 bool
+
+	// HashComments removes the line comments starting with '#', as done by
+	// [SanitizeHashComments].
+	HashComments bool
+
+	// Strict reports a stray '/' or '*' as a [*SyntaxError], as done by
+	// [SanitizeStrict].
+	Strict bool
+
+	// KeepOffsets replaces the comments with spaces instead of removing them,
+	// as done by [SanitizeKeepOffsets]. It is only used by [Options.Sanitize],
+	// since [Options.Unmarshal] always reports the errors at their original
+	// position. Note that the JSON5 conversion may still change the offsets.
+	KeepOffsets bool
+
+	// SkipUTF8Validation disables the UTF-8 validation of the data. Invalid
+	// UTF-8 sequences are copied to the output as they are, leaving their
+	// handling to the JSON library.
+	SkipUTF8Validation bool
+
+	// AlwaysSanitize disables the [HasComments] check in
+	// [Options.Unmarshal], sanitizing the data even if it does not seem to
+	// contain comments. This avoids to scan the data twice when comments are
+	// expected, and ensures that the data is always validated.
+	AlwaysSanitize bool
+}
+
+// SanitizeWithOptions is like [Sanitize], but it uses the given options.
+// It is a shorthand for opts.Sanitize(data).
+func SanitizeWithOptions(data []byte, opts Options) ([]byte, error) {
+	return opts.Sanitize(data)
+}
+
+// UnmarshalWithOptions is like [Unmarshal], but it uses the given options.
+// It is a shorthand for opts.Unmarshal(data, v).
+func UnmarshalWithOptions(data []byte, v any, opts Options) error {
+	return opts.Unmarshal(data, v)
+}
+
+// Sanitize removes all comments from data according to the options.
+// See [Sanitize].
+func (o Options) Sanitize(data []byte) ([]byte, error) {
+	if o.JSON5 {
+		out, _, err := sanitizeJSON5(data, o.scanner(), o.KeepOffsets)
+		return out, err
+	}
+	return stripAll(data, o.scanner(), o.KeepOffsets)
+}
+
+// Unmarshal parses the data according to the options and stores the result
+// in the value pointed by v. See [Unmarshal].
+//
+// Unless AlwaysSanitize is true, the data is sanitized only if it contains
+// any comment. Since trailing commas and JSON5 constructs cannot be
+// detected without scanning the data, it is always sanitized if
+// TrailingCommas or JSON5 is true.
+func (o Options) Unmarshal(data []byte, v any) error {
+	if o.JSON5 {
+		out, original, err := sanitizeJSON5(data, o.scanner(), false)
+		if err != nil {
+			return err
+		}
+		return unmarshalSanitized(data, out, original, v)
+	}
+	var s *scanner
+	if o.AlwaysSanitize || o.TrailingCommas || hasComments(data, o.HashComments) {
+		s = o.scanner()
+	}
+	return unmarshal(data, v, s)
+}
+
+// scanner returns a new scanner configured according to the options.
+func (o Options) scanner() *scanner {
+	return &scanner{
+		strict:         o.Strict,
+		trailingCommas: o.TrailingCommas,
+		hash:           o.HashComments,
+		skipUTF8:       o.SkipUTF8Validation,
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionsSanitize(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Opts Options
+		Data string
+		Want string
+	}{
+		{"Zero", Options{}, "[1, /* x */ 2,] // y", "[1,  2,] "},
+		{"TrailingCommas", Options{TrailingCommas: true}, "[1, /* x */ 2,] // y", "[1,  2] "},
+		{"HashComments", Options{HashComments: true}, "# x\n[1, 2] // y", "\n[1, 2] "},
+		{"KeepOffsets", Options{KeepOffsets: true}, "[1, /* x */ 2] // y", "[1,         2]     "},
+		{"KeepOffsetsTrailingCommas", Options{KeepOffsets: true, TrailingCommas: true}, "[1, 2, /* x */]", "[1, 2         ]"},
+		{"JSON5", Options{JSON5: true}, "{a: 'b', // c\n}", "{\"a\": \"b\" \n}"},
+		{"JSON5HashComments", Options{JSON5: true, HashComments: true}, "{a: 'b', # c\n}", "{\"a\": \"b\" \n}"},
+		{"SkipUTF8Validation", Options{SkipUTF8Validation: true}, "[\"\xa5\"] // \xa5", "[\"\xa5\"] "},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			s, err := tt.Opts.Sanitize([]byte(tt.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(s))
+			s, err = SanitizeWithOptions([]byte(tt.Data), tt.Opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(s))
+		})
+	}
+}
+
+func TestOptionsSanitizeError(t *testing.T) {
+	t.Parallel()
+	t.Run("InvalidUTF8", func(t *testing.T) {
+		t.Parallel()
+		_, err := Options{}.Sanitize([]byte("[\"\xa5\"]"))
+		assert.ErrorIs(t, err, ErrInvalidUTF8)
+	})
+	t.Run("Strict", func(t *testing.T) {
+		t.Parallel()
+		_, err := Options{Strict: true}.Sanitize([]byte(`{"a": 1 / 2}`))
+		var serr *SyntaxError
+		require.ErrorAs(t, err, &serr)
+		assert.Equal(t, SyntaxError{reasonStraySlash, 8, 1, 9}, *serr)
+	})
+}
+
+func TestOptionsUnmarshal(t *testing.T) {
+	t.Parallel()
+	t.Run("Small", func(t *testing.T) {
+		t.Parallel()
+		j := Small{}
+		require.NoError(t, Options{AlwaysSanitize: true}.Unmarshal(_small, &j))
+		FieldsValue(t, j)
+	})
+	t.Run("Medium", func(t *testing.T) {
+		t.Parallel()
+		j := Medium{}
+		require.NoError(t, UnmarshalWithOptions(_medium, &j, Options{Strict: true}))
+		FieldsValue(t, j)
+	})
+	t.Run("Dialects", func(t *testing.T) {
+		t.Parallel()
+		for _, opts := range []Options{
+			{TrailingCommas: true, HashComments: true},
+			{JSON5: true, HashComments: true},
+		} {
+			var v struct{ A, B string }
+			require.NoError(t, opts.Unmarshal([]byte("# a\n{\"a\": \"#1\", // b\n\"b\": \"2\",}"), &v))
+			assert.Equal(t, "#1", v.A)
+			assert.Equal(t, "2", v.B)
+		}
+	})
+	t.Run("AlwaysSanitize", func(t *testing.T) {
+		t.Parallel()
+		var v any
+		data := []byte("[\"\xa5\"]")
+		assert.NoError(t, Options{}.Unmarshal(data, &v), "UTF-8 must not be checked without comments")
+		assert.ErrorIs(t, Options{AlwaysSanitize: true}.Unmarshal(data, &v), ErrInvalidUTF8)
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		var v struct{ A int }
+		err := Options{HashComments: true}.Unmarshal([]byte("# a\n# b\n{\"a\": \"x\"}"), &v)
+		var perr *PositionError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 3, perr.Line)
+	})
+}
@@ -0,0 +1,306 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned by [ApplyPatch] if the patch document is
+	// malformed, as an operation without a required member.
+	ErrInvalidPatch = errors.New("jsonc: invalid patch")
+
+	// ErrTestFailed is returned by [ApplyPatch] if the value of a "test"
+	// operation does not match the target value.
+	ErrTestFailed = errors.New("jsonc: test operation failed")
+)
+
+// PatchError is returned by [ApplyPatch] if an operation cannot be applied.
+type PatchError struct {
+	// Index is the index of the operation in the patch document.
+	Index int
+
+	// Op and Path are the "op" and the "path" members of the operation.
+	Op, Path string
+
+	// Err is the reason of the error, as [ErrNotFound] if the target value
+	// does not exist.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("jsonc: patch operation %d (%s %q): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+// Unwrap returns the reason of the error.
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyPatch applies the JSON Patch (RFC 6902) to the JSONC data, returning
+// the patched data. The operations add, remove, replace, move, copy and
+// test are applied in order as done by [Modify], so that the comments and
+// the formatting of the data are preserved, except for the values written
+// by the operations. The patch document can contain comments too.
+//
+// The values moved or copied are written without the comments inside them,
+// and the result of the "test" operation compares the decoded values, so
+// that numbers are equal if they have the same value as float64.
+//
+// It returns the errors reported by [Parse] for the data or the patch
+// document, [ErrInvalidPatch] if the patch document is not an array of
+// operations, and a [*PatchError] if an operation cannot be applied. If any
+// operation fails, the data is not modified.
+func ApplyPatch(data, patch []byte) ([]byte, error) {
+	ops, err := parsePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		if data, err = op.apply(data); err != nil {
+			return nil, &PatchError{Index: i, Op: op.op, Path: op.path, Err: err}
+		}
+	}
+	return data, nil
+}
+
+// patchOperation is an operation of a JSON Patch.
+type patchOperation struct {
+	op    string
+	path  string
+	from  *string
+	value rawJSON // nil if missing
+}
+
+// rawJSON is a JSON-encoded value, written as it is by json.Marshal.
+type rawJSON []byte
+
+// MarshalJSON implements the json.Marshaler interface.
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	return r, nil
+}
+
+// parsePatch returns the operations of the JSON Patch document.
+func parsePatch(patch []byte) ([]patchOperation, error) {
+	doc, err := Parse(patch)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Value.Kind != KindArray {
+		return nil, fmt.Errorf("%w: not an array", ErrInvalidPatch)
+	}
+	ops := make([]patchOperation, len(doc.Value.Elements))
+	for i, e := range doc.Value.Elements {
+		if e.Value.Kind != KindObject {
+			return nil, fmt.Errorf("%w: operation %d is not an object", ErrInvalidPatch, i)
+		}
+		op := &ops[i]
+		var hasOp, hasPath bool
+		for _, m := range e.Value.Members {
+			var dst *string
+			switch m.Name() {
+			case "op":
+				dst, hasOp = &op.op, true
+			case "path":
+				dst, hasPath = &op.path, true
+			case "from":
+				op.from = new(string)
+				dst = op.from
+			case "value":
+				op.value = compactValue(m.Value)
+				continue
+			default:
+				continue
+			}
+			if m.Value.Kind != KindString {
+				return nil, fmt.Errorf("%w: member %q of operation %d is not a string", ErrInvalidPatch, m.Name(), i)
+			}
+			*dst = unquote(m.Value.Raw)
+		}
+		switch {
+		case !hasOp, !hasPath:
+			return nil, fmt.Errorf("%w: operation %d without \"op\" or \"path\"", ErrInvalidPatch, i)
+		case op.value == nil && (op.op == "add" || op.op == "replace" || op.op == "test"):
+			return nil, fmt.Errorf("%w: %s operation %d without \"value\"", ErrInvalidPatch, op.op, i)
+		case op.from == nil && (op.op == "move" || op.op == "copy"):
+			return nil, fmt.Errorf("%w: %s operation %d without \"from\"", ErrInvalidPatch, op.op, i)
+		}
+	}
+	return ops, nil
+}
+
+// compactValue returns the compact JSON encoding of n, without comments.
+func compactValue(n *Node) rawJSON {
+	var c compacter
+	c.node(n)
+	return c.buf
+}
+
+// apply applies the operation to data.
+func (op *patchOperation) apply(data []byte) ([]byte, error) {
+	switch op.op {
+	case "add":
+		return patchAdd(data, op.path, op.value)
+	case "remove":
+		return patchSet(data, op.path, Remove)
+	case "replace":
+		return patchSet(data, op.path, op.value)
+	case "move":
+		if *op.from == op.path {
+			_, err := patchValue(data, op.path)
+			return data, err
+		}
+		if strings.HasPrefix(op.path, *op.from+"/") {
+			return nil, fmt.Errorf("%w: cannot move %q into itself", ErrInvalidPath, *op.from)
+		}
+		v, err := patchValue(data, *op.from)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = patchSet(data, *op.from, Remove); err != nil {
+			return nil, err
+		}
+		return patchAdd(data, op.path, v)
+	case "copy":
+		v, err := patchValue(data, *op.from)
+		if err != nil {
+			return nil, err
+		}
+		return patchAdd(data, op.path, v)
+	case "test":
+		v, err := patchValue(data, op.path)
+		if err != nil {
+			return nil, err
+		}
+		var got, want any
+		if err := Unmarshal(v, &got); err != nil {
+			return nil, err
+		}
+		if err := Unmarshal(op.value, &want); err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(got, want) {
+			return nil, ErrTestFailed
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidPatch, op.op)
+}
+
+// patchValue returns the value of data referenced by the JSON Pointer.
+func patchValue(data []byte, pointer string) (rawJSON, error) {
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	_, n, err := patchPath(doc.Value, pointer, false)
+	if err != nil {
+		return nil, err
+	}
+	return compactValue(n), nil
+}
+
+// patchAdd adds value to data at the location referenced by the JSON
+// Pointer, inserting it if the parent is an array.
+func patchAdd(data []byte, pointer string, value rawJSON) ([]byte, error) {
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	path, _, err := patchPath(doc.Value, pointer, true)
+	if err != nil {
+		return nil, err
+	}
+	var opts ModifyOptions
+	if len(path) > 0 {
+		_, opts.Insert = path[len(path)-1].(int)
+	}
+	return patchEdit(data, doc, path, value, opts)
+}
+
+// patchSet replaces the existing value of data referenced by the JSON
+// Pointer with value, or removes it if value is [Remove].
+func patchSet(data []byte, pointer string, value any) ([]byte, error) {
+	doc, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	path, _, err := patchPath(doc.Value, pointer, false)
+	if err != nil {
+		return nil, err
+	}
+	return patchEdit(data, doc, path, value, ModifyOptions{})
+}
+
+// patchEdit applies the edits of [Modify] to data.
+func patchEdit(data []byte, doc *Document, path []any, value any, opts ModifyOptions) ([]byte, error) {
+	edits, err := modifyDocument(data, doc, path, value, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ApplyEdits(data, edits)
+}
+
+// patchPath returns the path, as the one used by [Modify], and the value
+// referenced by the JSON Pointer in root. If add is true, the value can be
+// missing, as long as its parent exists: in that case the returned node is
+// nil, and the last reference token can be "-" or the length of an array
+// to append to it.
+func patchPath(root *Node, pointer string, add bool) ([]any, *Node, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := make([]any, 0, len(tokens))
+	n := root
+	for i, token := range tokens {
+		last := add && i == len(tokens)-1
+		switch n.Kind {
+		case KindObject:
+			path = append(path, token)
+			j := memberIndex(n, token)
+			if j < 0 {
+				if last {
+					return path, nil, nil
+				}
+				return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, pointer)
+			}
+			n = n.Members[j].Value
+		case KindArray:
+			j, ok := arrayIndex(token)
+			if token == "-" {
+				j, ok = len(n.Elements), true
+			}
+			switch {
+			case !ok:
+				return nil, nil, fmt.Errorf("%w: invalid array index %q", ErrInvalidPath, token)
+			case last && j == len(n.Elements):
+				return append(path, j), nil, nil
+			case j >= len(n.Elements):
+				return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, pointer)
+			}
+			path = append(path, j)
+			n = n.Elements[j].Value
+		default:
+			return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, pointer)
+		}
+	}
+	return path, n, nil
+}
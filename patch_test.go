@@ -0,0 +1,132 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name  string
+		Data  string
+		Patch string
+		Want  string
+	}{
+		{"Add", "{\n  // a\n  \"a\": 1\n}", `[{"op": "add", "path": "/b", "value": 2}]`, "{\n  // a\n  \"a\": 1,\n  \"b\": 2\n}"},
+		{"AddAfterLineComment", "{\"a\": 1 // c\n}", `[{"op": "add", "path": "/b", "value": 2}, {"op": "test", "path": "/b", "value": 2}]`, "{\"a\": 1, // c\n  \"b\": 2\n}"},
+		{"AddReplace", `{"a": 1 /* c */}`, `[{"op": "add", "path": "/a", "value": [1]}]`, `{"a": [1] /* c */}`},
+		{"AddArrayIndex", `["a", /* c */ "c"]`, `[{"op": "add", "path": "/1", "value": "b"}]`, `["a", /* c */ "b", "c"]`},
+		{"AddArrayEnd", `[1]`, `[{"op": "add", "path": "/-", "value": 2}]`, `[1, 2]`},
+		{"AddArrayLength", `[1]`, `[{"op": "add", "path": "/1", "value": 2}]`, `[1, 2]`},
+		{"AddRoot", "// c\n{}", `[{"op": "add", "path": "", "value": [1]}]`, "// c\n[1]"},
+		{"AddEscapedKey", `{}`, `[{"op": "add", "path": "/a~1b~0c", "value": 1}]`, `{"a/b~c": 1}`},
+		{"AddValueComments", `{}`, `[{"op": "add", "path": "/a", /* c */ "value": { /* d */ "b": 1 }}]`, `{"a": {"b":1}}`},
+		{"Remove", "{\n  \"a\": 1,\n  // b\n  \"b\": 2,\n  \"c\": 3 // c\n}", `[{"op": "remove", "path": "/b"}]`, "{\n  \"a\": 1,\n  \"c\": 3 // c\n}"},
+		{"RemoveLast", "{\"a\": 1, // a\n \"b\": 2}", `[{"op": "remove", "path": "/b"}]`, "{\"a\": 1 // a\n}"},
+		{"RemoveLastElement", "[\n  1, // one\n  2\n]", `[{"op": "remove", "path": "/1"}]`, "[\n  1 // one\n]"},
+		{"RemoveArray", `[1, 2, 3]`, `[{"op": "remove", "path": "/1"}]`, `[1, 3]`},
+		{"Replace", "{\n  \"a\": 1 // c\n}", `[{"op": "replace", "path": "/a", "value": "x"}]`, "{\n  \"a\": \"x\" // c\n}"},
+		{"ReplaceArray", `[1, 2]`, `[{"op": "replace", "path": "/0", "value": 0}]`, `[0, 2]`},
+		{"Move", "{\n  \"a\": {\"b\": 1},\n  // c\n  \"c\": 2\n}", `[{"op": "move", "from": "/a/b", "path": "/d"}]`, "{\n  \"a\": {},\n  // c\n  \"c\": 2,\n  \"d\": 1\n}"},
+		{"MoveLast", "{\n  \"a\": {},\n  \"b\": 1, // b\n  \"c\": 2\n}", `[{"op": "move", "from": "/c", "path": "/a/c"}]`, "{\n  \"a\": {\n    \"c\": 2\n  },\n  \"b\": 1 // b\n}"},
+		{"MoveArray", `[1, 2, 3, 4]`, `[{"op": "move", "from": "/1", "path": "/3"}]`, `[1, 3, 4, 2]`},
+		{"MoveSelf", `{"a": 1}`, `[{"op": "move", "from": "/a", "path": "/a"}]`, `{"a": 1}`},
+		{"Copy", `{"a": [1, /* c */ 2]}`, `[{"op": "copy", "from": "/a", "path": "/b"}]`, `{"a": [1, /* c */ 2], "b": [1,2]}`},
+		{"Test", `{"a": {"b": [1, "x"]}}`, `[{"op": "test", "path": "/a", "value": {"b": [1.0, "x"]}}]`, `{"a": {"b": [1, "x"]}}`},
+		{"Sequence", "{\n  // a\n  \"a\": 1\n}", `[
+			{"op": "test", "path": "/a", "value": 1},
+			{"op": "add", "path": "/b", "value": []},
+			{"op": "add", "path": "/b/-", "value": 1},
+			{"op": "replace", "path": "/a", "value": 2}, // trailing comma
+		]`, "{\n  // a\n  \"a\": 2,\n  \"b\": [\n    1\n  ]\n}"},
+		{"Empty", `{"a": 1}`, `[]`, `{"a": 1}`},
+		{"IgnoreUnknownMembers", `{}`, `[{"op": "add", "path": "/a", "value": 1, "x": true}]`, `{"a": 1}`},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ApplyPatch([]byte(tt.Data), []byte(tt.Patch))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(got))
+		})
+	}
+}
+
+func TestApplyPatchError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name  string
+		Data  string
+		Patch string
+		Want  error
+	}{
+		{"NotArray", `{}`, `{}`, ErrInvalidPatch},
+		{"NotObject", `{}`, `[1]`, ErrInvalidPatch},
+		{"MissingOp", `{}`, `[{"path": "/a"}]`, ErrInvalidPatch},
+		{"MissingPath", `{}`, `[{"op": "remove"}]`, ErrInvalidPatch},
+		{"MissingValue", `{}`, `[{"op": "add", "path": "/a"}]`, ErrInvalidPatch},
+		{"MissingFrom", `{}`, `[{"op": "copy", "path": "/a"}]`, ErrInvalidPatch},
+		{"NotString", `{}`, `[{"op": "remove", "path": 1}]`, ErrInvalidPatch},
+		{"UnknownOp", `{}`, `[{"op": "x", "path": "/a"}]`, ErrInvalidPatch},
+		{"AddMissingParent", `{}`, `[{"op": "add", "path": "/a/b", "value": 1}]`, ErrNotFound},
+		{"AddOutOfRange", `[1]`, `[{"op": "add", "path": "/2", "value": 1}]`, ErrNotFound},
+		{"AddLeadingZero", `[1]`, `[{"op": "add", "path": "/01", "value": 1}]`, ErrInvalidPath},
+		{"AddInvalidPointer", `{}`, `[{"op": "add", "path": "a", "value": 1}]`, ErrInvalidPath},
+		{"RemoveMissing", `{}`, `[{"op": "remove", "path": "/a"}]`, ErrNotFound},
+		{"RemoveEnd", `[1]`, `[{"op": "remove", "path": "/-"}]`, ErrNotFound},
+		{"RemoveRoot", `{}`, `[{"op": "remove", "path": ""}]`, ErrInvalidPath},
+		{"ReplaceMissing", `{}`, `[{"op": "replace", "path": "/a", "value": 1}]`, ErrNotFound},
+		{"MoveMissing", `{}`, `[{"op": "move", "from": "/a", "path": "/b"}]`, ErrNotFound},
+		{"MoveIntoChild", `{"a": {}}`, `[{"op": "move", "from": "/a", "path": "/a/b"}]`, ErrInvalidPath},
+		{"CopyMissing", `{}`, `[{"op": "copy", "from": "/a", "path": "/b"}]`, ErrNotFound},
+		{"TestMissing", `{}`, `[{"op": "test", "path": "/a", "value": 1}]`, ErrNotFound},
+		{"TestFailed", `{"a": "1"}`, `[{"op": "test", "path": "/a", "value": 1}]`, ErrTestFailed},
+		{"TraverseScalar", `{"a": 1}`, `[{"op": "add", "path": "/a/b", "value": 1}]`, ErrNotFound},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := ApplyPatch([]byte(tt.Data), []byte(tt.Patch))
+			assert.ErrorIs(t, err, tt.Want)
+		})
+	}
+	t.Run("PatchError", func(t *testing.T) {
+		t.Parallel()
+		_, err := ApplyPatch([]byte(`{"a": 1}`), []byte(`[{"op": "remove", "path": "/a"}, {"op": "remove", "path": "/a"}]`))
+		var perr *PatchError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 1, perr.Index)
+		assert.Equal(t, "remove", perr.Op)
+		assert.Equal(t, "/a", perr.Path)
+		assert.ErrorIs(t, perr, ErrNotFound)
+		assert.Equal(t, `jsonc: patch operation 1 (remove "/a"): jsonc: value not found: /a`, perr.Error())
+	})
+	t.Run("Syntax", func(t *testing.T) {
+		t.Parallel()
+		var serr *SyntaxError
+		_, err := ApplyPatch([]byte(`{"a": }`), []byte(`[{"op": "remove", "path": "/a"}]`))
+		assert.ErrorAs(t, err, &serr)
+		_, err = ApplyPatch([]byte(`{}`), []byte(`[`))
+		assert.ErrorAs(t, err, &serr)
+	})
+}
@@ -0,0 +1,309 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned by [Get] and [GetValue] if the JSON Pointer does
+// not reference any value of the data.
+var ErrNotFound = errors.New("jsonc: value not found")
+
+// Get returns the raw value referenced by the JSON Pointer (RFC 6901) in
+// the JSONC data, along with its byte range. The raw value is a slice of
+// data: if it is an object or an array, it includes the comments inside it.
+//
+// The data is scanned without building any Go value, stopping at the
+// referenced value once its object or array is scanned. If an object has
+// duplicate keys, the last one is used, as done by [Unmarshal].
+//
+// It returns [ErrInvalidPath] if the pointer is malformed, [ErrNotFound] if
+// it does not reference any value, and a [*SyntaxError] if the scanned data
+// is malformed.
+func Get(data []byte, pointer string) ([]byte, Range, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, Range{}, err
+	}
+	p := parser{data: data}
+	for _, token := range tokens {
+		if err := p.skipTrivia(); err != nil {
+			return nil, Range{}, err
+		}
+		var found bool
+		switch p.peek(0) {
+		case '{':
+			found, err = p.member(token)
+		case '[':
+			found, err = p.element(token)
+		}
+		if err != nil {
+			return nil, Range{}, err
+		}
+		if !found {
+			return nil, Range{}, fmt.Errorf("%w: %s", ErrNotFound, pointer)
+		}
+	}
+	if err := p.skipTrivia(); err != nil {
+		return nil, Range{}, err
+	}
+	start := p.off
+	if err := p.skipValue(); err != nil {
+		return nil, Range{}, err
+	}
+	return data[start:p.off], Range{start, p.off}, nil
+}
+
+// GetValue decodes the value referenced by the JSON Pointer in the JSONC
+// data into v, as done by [Unmarshal], without decoding the rest of the
+// data. The positions of the errors reported by the JSON library are
+// relative to data.
+//
+// It returns the errors reported by [Get] and [Unmarshal].
+func GetValue(data []byte, pointer string, v any) error {
+	raw, r, err := Get(data, pointer)
+	if err != nil {
+		return err
+	}
+	sanitized, original := raw, func(off int64) int64 { return off }
+	if HasComments(raw) {
+		var offsets offsetMap
+		if sanitized, err = stripAll(raw, &scanner{offsets: &offsets}, false); err != nil {
+			return err
+		}
+		original = offsets.original
+	}
+	start := int64(r.Start)
+	return unmarshalSanitized(data, sanitized, func(off int64) int64 {
+		return start + original(off)
+	}, v)
+}
+
+// parsePointer returns the reference tokens of the JSON Pointer, unescaped.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("%w: JSON Pointer %q does not start with '/'", ErrInvalidPath, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || t[j+1] != '0' && t[j+1] != '1') {
+				return nil, fmt.Errorf("%w: invalid escape in JSON Pointer %q", ErrInvalidPath, pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex returns the array index represented by the reference token,
+// or false if it is not a valid index.
+func arrayIndex(token string) (int, bool) {
+	if token == "" || len(token) > 1 && token[0] == '0' {
+		return 0, false
+	}
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return 0, false
+		}
+	}
+	i, err := strconv.Atoi(token)
+	return i, err == nil
+}
+
+// member moves to the value of the last member of the object at the
+// current offset with the given name, reporting whether it exists.
+func (p *parser) member(name string) (bool, error) {
+	if err := p.open(); err != nil {
+		return false, err
+	}
+	found := -1
+	for {
+		if err := p.skipTrivia(); err != nil {
+			return false, err
+		}
+		if p.peek(0) == '}' {
+			p.close()
+			break
+		}
+		if p.peek(0) != '"' {
+			return false, p.unexpectedOrEOF()
+		}
+		start := p.off
+		if err := p.string(); err != nil {
+			return false, err
+		}
+		match := keyEqual(p.data[start:p.off], name)
+		if err := p.skipTrivia(); err != nil {
+			return false, err
+		}
+		if err := p.expect(':'); err != nil {
+			return false, err
+		}
+		if err := p.skipTrivia(); err != nil {
+			return false, err
+		}
+		if match {
+			found = p.off
+		}
+		if err := p.skipValue(); err != nil {
+			return false, err
+		}
+		if err := p.skipSeparator('}'); err != nil {
+			return false, err
+		}
+	}
+	if found < 0 {
+		return false, nil
+	}
+	p.off = found
+	return true, nil
+}
+
+// element moves to the element of the array at the current offset with the
+// index represented by the reference token, reporting whether it exists.
+func (p *parser) element(token string) (bool, error) {
+	index, ok := arrayIndex(token)
+	if !ok {
+		return false, nil
+	}
+	if err := p.open(); err != nil {
+		return false, err
+	}
+	for i := 0; ; i++ {
+		if err := p.skipTrivia(); err != nil {
+			return false, err
+		}
+		if p.peek(0) == ']' {
+			return false, nil
+		}
+		if i == index {
+			return true, nil
+		}
+		if err := p.skipValue(); err != nil {
+			return false, err
+		}
+		if err := p.skipSeparator(']'); err != nil {
+			return false, err
+		}
+	}
+}
+
+// skipValue skips the value at the current offset.
+func (p *parser) skipValue() error {
+	switch c := p.peek(0); {
+	case c == '{':
+		if err := p.open(); err != nil {
+			return err
+		}
+		for {
+			if err := p.skipTrivia(); err != nil {
+				return err
+			}
+			if p.peek(0) == '}' {
+				p.close()
+				return nil
+			}
+			if p.peek(0) != '"' {
+				return p.unexpectedOrEOF()
+			}
+			if err := p.string(); err != nil {
+				return err
+			}
+			if err := p.skipTrivia(); err != nil {
+				return err
+			}
+			if err := p.expect(':'); err != nil {
+				return err
+			}
+			if err := p.skipTrivia(); err != nil {
+				return err
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+			if err := p.skipSeparator('}'); err != nil {
+				return err
+			}
+		}
+	case c == '[':
+		if err := p.open(); err != nil {
+			return err
+		}
+		for {
+			if err := p.skipTrivia(); err != nil {
+				return err
+			}
+			if p.peek(0) == ']' {
+				p.close()
+				return nil
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+			if err := p.skipSeparator(']'); err != nil {
+				return err
+			}
+		}
+	case c == '"':
+		return p.string()
+	case c == '-' || '0' <= c && c <= '9':
+		return p.number()
+	case p.literal("true"), p.literal("false"), p.literal("null"):
+		return nil
+	}
+	return p.unexpectedOrEOF()
+}
+
+// skipSeparator skips the white spaces, the comments and the comma
+// following a member or an element.
+func (p *parser) skipSeparator(end byte) error {
+	if err := p.skipTrivia(); err != nil {
+		return err
+	}
+	switch p.peek(0) {
+	case ',':
+		p.off++
+	case end:
+	default:
+		return p.unexpectedOrEOF()
+	}
+	return nil
+}
+
+// unexpectedOrEOF returns the error for the byte at the current offset, or
+// for the unexpected end of the data.
+func (p *parser) unexpectedOrEOF() error {
+	if p.off == len(p.data) {
+		return p.error(p.off, reasonUnexpectedEOF)
+	}
+	return p.unexpected()
+}
+
+// keyEqual reports whether the JSON string literal raw represents name.
+func keyEqual(raw []byte, name string) bool {
+	if bytes.IndexByte(raw, '\\') < 0 {
+		return string(raw[1:len(raw)-1]) == name
+	}
+	return unquote(raw) == name
+}
@@ -0,0 +1,178 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// _pointerData is the example of RFC 6901 with comments.
+var _pointerData = []byte(`{
+  // comment
+  "foo": ["bar", /* comment */ "baz"],
+  "": 0,
+  "a/b": 1,
+  "c%d": 2,
+  "e^f": 3,
+  "g|h": 4,
+  "i\\j": 5,
+  "k\"l": 6,
+  " ": 7,
+  "m~n": 8, // comment
+  "dup": 1,
+  "dup": 2,
+}`)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Pointer string
+		Want    string
+	}{
+		{"", string(_pointerData)},
+		{"/foo", `["bar", /* comment */ "baz"]`},
+		{"/foo/0", `"bar"`},
+		{"/foo/1", `"baz"`},
+		{"/", "0"},
+		{"/a~1b", "1"},
+		{"/c%d", "2"},
+		{"/e^f", "3"},
+		{"/g|h", "4"},
+		{"/i\\j", "5"},
+		{"/k\"l", "6"},
+		{"/ ", "7"},
+		{"/m~0n", "8"},
+		{"/dup", "2"},
+	} {
+		tt := tt
+		t.Run(tt.Pointer, func(t *testing.T) {
+			t.Parallel()
+			got, r, err := Get(_pointerData, tt.Pointer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(got))
+			assert.Equal(t, tt.Want, string(_pointerData[r.Start:r.End]))
+		})
+	}
+	t.Run("Medium", func(t *testing.T) {
+		t.Parallel()
+		got, _, err := Get(_medium, "/telemetry.telemetryLevel")
+		require.NoError(t, err)
+		assert.Equal(t, `"all"`, string(got))
+		var want map[string]any
+		require.NoError(t, Unmarshal(_medium, &want))
+		for k, w := range want {
+			var v any
+			require.NoError(t, GetValue(_medium, "/"+strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1"), &v))
+			assert.Equal(t, w, v, k)
+		}
+	})
+}
+
+func TestGetError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name    string
+		Data    string
+		Pointer string
+		Want    error
+	}{
+		{"NoSlash", `{}`, "a", ErrInvalidPath},
+		{"InvalidEscape", `{}`, "/a~2", ErrInvalidPath},
+		{"TrailingTilde", `{}`, "/a~", ErrInvalidPath},
+		{"MissingKey", `{"a": 1}`, "/b", ErrNotFound},
+		{"OutOfRange", `[1]`, "/1", ErrNotFound},
+		{"EndOfArray", `[1]`, "/-", ErrNotFound},
+		{"LeadingZero", `[1]`, "/01", ErrNotFound},
+		{"Scalar", `{"a": 1}`, "/a/b", ErrNotFound},
+		{"Empty", ``, "/a", ErrNotFound},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := Get([]byte(tt.Data), tt.Pointer)
+			assert.ErrorIs(t, err, tt.Want)
+		})
+	}
+	for _, tt := range [...]struct {
+		Name    string
+		Data    string
+		Pointer string
+		Want    SyntaxError
+	}{
+		{"UnexpectedEOF", `{"a": [1, 2`, "/a/5", SyntaxError{reasonUnexpectedEOF, 11, 1, 12}},
+		{"UnterminatedComment", `{"a": 1 /* }`, "/b", SyntaxError{reasonUnterminatedComment, 8, 1, 9}},
+		{"InvalidValue", `{"a": tru, "b": 1}`, "/b", SyntaxError{`unexpected character 't'`, 6, 1, 7}},
+		{"MissingColon", `{"a" 1}`, "/a", SyntaxError{`unexpected character '1'`, 5, 1, 6}},
+		{"InvalidNumber", `[-, 1]`, "/1", SyntaxError{reasonInvalidNumber, 1, 1, 2}},
+		{"EmptyRoot", ` `, "", SyntaxError{reasonUnexpectedEOF, 1, 1, 2}},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := Get([]byte(tt.Data), tt.Pointer)
+			var serr *SyntaxError
+			require.ErrorAs(t, err, &serr)
+			assert.Equal(t, tt.Want, *serr)
+		})
+	}
+}
+
+func TestGetValue(t *testing.T) {
+	t.Parallel()
+	var foo []string
+	require.NoError(t, GetValue(_pointerData, "/foo", &foo))
+	assert.Equal(t, []string{"bar", "baz"}, foo)
+	var n int
+	require.NoError(t, GetValue(_pointerData, "/m~0n", &n))
+	assert.Equal(t, 8, n)
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		var v struct{ A int }
+		err := GetValue([]byte("{\n  \"x\": {\n    // c\n    \"A\": \"s\"\n  }\n}"), "/x", &v)
+		var perr *PositionError
+		require.ErrorAs(t, err, &perr)
+		assert.Equal(t, 4, perr.Line)
+		assert.ErrorIs(t, GetValue(_pointerData, "/none", &v), ErrNotFound)
+	})
+}
+
+func BenchmarkGet(b *testing.B) {
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := Get(_medium, "/telemetry.telemetryLevel"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Unmarshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var v struct {
+				TelemetryLevel string `json:"telemetry.telemetryLevel"`
+			}
+			if err := Unmarshal(_medium, &v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
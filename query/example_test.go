@@ -0,0 +1,43 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query_test
+
+import (
+	"fmt"
+
+	"github.com/marcozac/go-jsonc/query"
+)
+
+func ExampleEval() {
+	data := []byte(`{
+		// Editor
+		"editor.fontSize": 14,
+		"editor.formatOnSave": true, // format on save
+		"editor.minimap.enabled": false,
+		"files.trimTrailingWhitespace": true
+	}`)
+
+	matches, err := query.Eval(data, `$[?(@key =~ '^editor\.' && @ == true)]`)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, m := range matches {
+		fmt.Printf("%s at line %d, column %d\n", m.Pointer(), m.Line, m.Column)
+	}
+
+	// Output:
+	// /editor.formatOnSave at line 4, column 26
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"reflect"
+	"regexp"
+)
+
+// expr is a filter expression.
+type expr interface {
+	// test reports whether the value it, whose root is root, matches the
+	// expression.
+	test(it, root item) bool
+}
+
+// logical is a conjunction or a disjunction of filter expressions.
+type logical struct {
+	or   bool
+	l, r expr
+}
+
+func (x logical) test(it, root item) bool {
+	if x.or {
+		return x.l.test(it, root) || x.r.test(it, root)
+	}
+	return x.l.test(it, root) && x.r.test(it, root)
+}
+
+// not is the negation of a filter expression.
+type not struct {
+	x expr
+}
+
+func (x not) test(it, root item) bool {
+	return !x.x.test(it, root)
+}
+
+// exists tests whether a path references a value.
+type exists struct {
+	path path
+}
+
+func (x exists) test(it, root item) bool {
+	_, ok := x.path.resolve(it, root)
+	return ok
+}
+
+// comparison compares two operands.
+type comparison struct {
+	op   string
+	l, r operand
+	re   *regexp.Regexp // for =~
+}
+
+func (x comparison) test(it, root item) bool {
+	l, lok := x.l.value(it, root)
+	r, rok := x.r.value(it, root)
+	switch x.op {
+	case "==":
+		return lok == rok && reflect.DeepEqual(l, r)
+	case "!=":
+		return lok != rok || !reflect.DeepEqual(l, r)
+	case "=~":
+		s, ok := l.(string)
+		return lok && ok && x.re.MatchString(s)
+	}
+	if !lok || !rok {
+		return false
+	}
+	var c int
+	switch l := l.(type) {
+	case float64:
+		r, ok := r.(float64)
+		if !ok {
+			return false
+		}
+		c = compare(l, r)
+	case string:
+		r, ok := r.(string)
+		if !ok {
+			return false
+		}
+		c = compare(l, r)
+	default:
+		return false
+	}
+	switch x.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+// compare returns -1, 0 or +1 if a is less than, equal to or greater than
+// b.
+func compare[T float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// operand is an operand of a comparison.
+type operand interface {
+	// value returns the value of the operand for the value it, whose root
+	// is root, or false if it does not exist.
+	value(it, root item) (any, bool)
+}
+
+// literal is a number, a string, a boolean or null.
+type literal struct {
+	v any
+}
+
+func (x literal) value(item, item) (any, bool) {
+	return x.v, true
+}
+
+// key is the name or the index of the filtered value (@key).
+type key struct{}
+
+func (key) value(it, _ item) (any, bool) {
+	switch k := it.key().(type) {
+	case string:
+		return k, true
+	case int:
+		return float64(k), true
+	}
+	return nil, false
+}
+
+// path is the path of a single value, relative to the filtered value or to
+// the root.
+type path struct {
+	root  bool
+	steps []selector
+}
+
+func (x path) value(it, root item) (any, bool) {
+	if it, ok := x.resolve(it, root); ok {
+		return value(it.node), true
+	}
+	return nil, false
+}
+
+// resolve returns the value referenced by the path.
+func (x path) resolve(it, root item) (item, bool) {
+	if x.root {
+		it = root
+	}
+	for _, s := range x.steps {
+		var ok bool
+		if s.name != nil {
+			it, ok = it.member(*s.name)
+		} else {
+			it, ok = it.element(s.index)
+		}
+		if !ok {
+			return item{}, false
+		}
+	}
+	return it, true
+}
@@ -0,0 +1,470 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// SyntaxError describes a malformed query.
+type SyntaxError struct {
+	// Reason is the description of the error.
+	Reason string
+
+	// Offset is the byte offset in the query at which the error occurred.
+	Offset int
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query: %s at offset %d", e.Reason, e.Offset)
+}
+
+// parser is a recursive descent parser of queries.
+type parser struct {
+	expr string
+	off  int
+}
+
+// query parses the whole query.
+func (p *parser) query() ([]segment, error) {
+	p.space()
+	if !p.consume("$") {
+		return nil, p.error("query must start with $")
+	}
+	var segments []segment
+	for p.space(); p.off < len(p.expr); p.space() {
+		var s segment
+		var err error
+		switch {
+		case p.consume(".."):
+			s.descendant = true
+			if p.peek() == '[' {
+				s.selectors, err = p.bracket()
+			} else {
+				s.selectors, err = p.dotSelector()
+			}
+		case p.consume("."):
+			s.selectors, err = p.dotSelector()
+		case p.peek() == '[':
+			s.selectors, err = p.bracket()
+		default:
+			return nil, p.unexpected()
+		}
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, s)
+	}
+	return segments, nil
+}
+
+// dotSelector parses the selector following a dot: a name or a wildcard.
+func (p *parser) dotSelector() ([]selector, error) {
+	if p.consume("*") {
+		return []selector{{wildcard: true}}, nil
+	}
+	name := p.name()
+	if name == "" {
+		return nil, p.unexpected()
+	}
+	return []selector{{name: &name}}, nil
+}
+
+// bracket parses a bracketed selection: a filter or a list of selectors.
+func (p *parser) bracket() ([]selector, error) {
+	p.off++ // [
+	p.space()
+	var selectors []selector
+	if p.consume("?") {
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector{filter: f})
+	} else {
+		for {
+			p.space()
+			var s selector
+			switch c := p.peek(); {
+			case c == '*':
+				p.off++
+				s.wildcard = true
+			case c == '\'' || c == '"':
+				name, err := p.string()
+				if err != nil {
+					return nil, err
+				}
+				s.name = &name
+			case c == '-' || '0' <= c && c <= '9':
+				i, err := p.integer()
+				if err != nil {
+					return nil, err
+				}
+				s.index = i
+			default:
+				return nil, p.unexpected()
+			}
+			selectors = append(selectors, s)
+			p.space()
+			if !p.consume(",") {
+				break
+			}
+		}
+	}
+	p.space()
+	if !p.consume("]") {
+		return nil, p.unexpected()
+	}
+	return selectors, nil
+}
+
+// or parses a filter expression.
+func (p *parser) or() (expr, error) {
+	l, err := p.and()
+	for err == nil {
+		p.space()
+		if !p.consume("||") {
+			break
+		}
+		var r expr
+		if r, err = p.and(); err == nil {
+			l = logical{or: true, l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+// and parses a conjunction of filter expressions.
+func (p *parser) and() (expr, error) {
+	l, err := p.unary()
+	for err == nil {
+		p.space()
+		if !p.consume("&&") {
+			break
+		}
+		var r expr
+		if r, err = p.unary(); err == nil {
+			l = logical{l: l, r: r}
+		}
+	}
+	return l, err
+}
+
+// unary parses a negation, a parenthesized filter expression or a
+// comparison.
+func (p *parser) unary() (expr, error) {
+	p.space()
+	switch {
+	case p.peek() == '!' && !strings.HasPrefix(p.expr[p.off:], "!="):
+		p.off++
+		x, err := p.unary()
+		return not{x}, err
+	case p.consume("("):
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		p.space()
+		if !p.consume(")") {
+			return nil, p.unexpected()
+		}
+		return x, nil
+	}
+	return p.comparison()
+}
+
+// comparisonOperators are the comparison operators, longest first.
+var comparisonOperators = [...]string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// comparison parses a comparison or an existence test.
+func (p *parser) comparison() (expr, error) {
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	var op string
+	for _, o := range comparisonOperators {
+		if p.consume(o) {
+			op = o
+			break
+		}
+	}
+	if op == "" {
+		if path, ok := l.(path); ok {
+			return exists{path}, nil
+		}
+		return nil, p.error("expected comparison operator")
+	}
+	p.space()
+	start := p.off
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	c := comparison{op: op, l: l, r: r}
+	if op == "=~" {
+		lit, ok := r.(literal)
+		s, isString := lit.v.(string)
+		if !ok || !isString {
+			p.off = start
+			return nil, p.error("expected regular expression string")
+		}
+		if c.re, err = regexp.Compile(s); err != nil {
+			p.off = start
+			return nil, p.error(err.Error())
+		}
+	}
+	return c, nil
+}
+
+// keywords are the literals true, false and null.
+var keywords = [...]struct {
+	s string
+	v any
+}{{"true", true}, {"false", false}, {"null", nil}}
+
+// operand parses an operand of a comparison.
+func (p *parser) operand() (operand, error) {
+	p.space()
+	switch c := p.peek(); {
+	case c == '@':
+		p.off++
+		if strings.HasPrefix(p.expr[p.off:], "key") && !isNameByte(p.peekAt(3)) {
+			p.off += 3
+			return key{}, nil
+		}
+		return p.path(false)
+	case c == '$':
+		p.off++
+		return p.path(true)
+	case c == '\'' || c == '"':
+		s, err := p.string()
+		return literal{s}, err
+	case c == '-' || '0' <= c && c <= '9':
+		return p.number()
+	}
+	for _, lit := range keywords {
+		if strings.HasPrefix(p.expr[p.off:], lit.s) && !isNameByte(p.peekAt(len(lit.s))) {
+			p.off += len(lit.s)
+			return literal{lit.v}, nil
+		}
+	}
+	return nil, p.unexpected()
+}
+
+// path parses the steps of a path of a single value.
+func (p *parser) path(root bool) (operand, error) {
+	pa := path{root: root}
+	for {
+		switch {
+		case p.peek() == '.' && p.peekAt(1) != '.':
+			p.off++
+			name := p.name()
+			if name == "" {
+				return nil, p.unexpected()
+			}
+			pa.steps = append(pa.steps, selector{name: &name})
+		case p.peek() == '[':
+			p.off++
+			p.space()
+			var s selector
+			if c := p.peek(); c == '\'' || c == '"' {
+				name, err := p.string()
+				if err != nil {
+					return nil, err
+				}
+				s.name = &name
+			} else {
+				i, err := p.integer()
+				if err != nil {
+					return nil, err
+				}
+				s.index = i
+			}
+			p.space()
+			if !p.consume("]") {
+				return nil, p.unexpected()
+			}
+			pa.steps = append(pa.steps, s)
+		default:
+			return pa, nil
+		}
+	}
+}
+
+// name parses a member name of the dot notation.
+func (p *parser) name() string {
+	start := p.off
+	for p.off < len(p.expr) && isNameByte(p.expr[p.off]) {
+		p.off++
+	}
+	return p.expr[start:p.off]
+}
+
+// isNameByte reports whether c can be part of a member name of the dot
+// notation.
+func isNameByte(c byte) bool {
+	return c == '_' || c == '-' || c >= utf8.RuneSelf ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// integer parses an array index.
+func (p *parser) integer() (int, error) {
+	start := p.off
+	if p.peek() == '-' {
+		p.off++
+	}
+	for '0' <= p.peek() && p.peek() <= '9' {
+		p.off++
+	}
+	i, err := strconv.Atoi(p.expr[start:p.off])
+	if err != nil {
+		p.off = start
+		return 0, p.error("invalid index")
+	}
+	return i, nil
+}
+
+// number parses a number literal.
+func (p *parser) number() (operand, error) {
+	start := p.off
+	for p.off < len(p.expr) && strings.IndexByte("+-.0123456789eE", p.expr[p.off]) >= 0 {
+		p.off++
+	}
+	f, err := strconv.ParseFloat(p.expr[start:p.off], 64)
+	if err != nil {
+		p.off = start
+		return nil, p.error("invalid number")
+	}
+	return literal{f}, nil
+}
+
+// string parses a single-quoted or double-quoted string literal, with the
+// escape sequences of JSON strings, plus \'. The other escape sequences are
+// kept as they are.
+func (p *parser) string() (string, error) {
+	quote := p.expr[p.off]
+	start := p.off
+	var b strings.Builder
+	for p.off++; p.off < len(p.expr); p.off++ {
+		c := p.expr[p.off]
+		switch {
+		case c == quote:
+			p.off++
+			return b.String(), nil
+		case c != '\\':
+			b.WriteByte(c)
+			continue
+		}
+		p.off++
+		switch c := p.peek(); c {
+		case '\'', '"', '\\', '/':
+			b.WriteByte(c)
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			r, ok := p.hex4(p.off + 1)
+			if !ok {
+				return "", p.error("invalid escape sequence")
+			}
+			p.off += 4
+			if utf16.IsSurrogate(r) {
+				if r2, ok := p.hex4(p.off + 3); ok && p.peekAt(1) == '\\' && p.peekAt(2) == 'u' {
+					if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+						r = dec
+						p.off += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			// kept as is, as in regular expressions
+			b.WriteByte('\\')
+			p.off--
+		}
+	}
+	p.off = start
+	return "", p.error("unterminated string")
+}
+
+// hex4 returns the rune represented by the 4 hexadecimal digits at the
+// given offset.
+func (p *parser) hex4(off int) (rune, bool) {
+	if off+4 > len(p.expr) {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(p.expr[off:off+4], 16, 32)
+	return rune(r), err == nil
+}
+
+// space skips the white spaces at the current offset.
+func (p *parser) space() {
+	for p.off < len(p.expr) && strings.IndexByte(" \t\r\n", p.expr[p.off]) >= 0 {
+		p.off++
+	}
+}
+
+// consume consumes s if the query continues with it.
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.expr[p.off:], s) {
+		p.off += len(s)
+		return true
+	}
+	return false
+}
+
+// peek returns the byte at the current offset, or 0 at the end.
+func (p *parser) peek() byte {
+	return p.peekAt(0)
+}
+
+// peekAt returns the byte at the given distance from the current offset, or
+// 0 if it is out of the query.
+func (p *parser) peekAt(i int) byte {
+	if p.off+i < len(p.expr) {
+		return p.expr[p.off+i]
+	}
+	return 0
+}
+
+// unexpected returns a [*SyntaxError] for the character at the current
+// offset.
+func (p *parser) unexpected() error {
+	if p.off == len(p.expr) {
+		return p.error("unexpected end of query")
+	}
+	r, _ := utf8.DecodeRuneInString(p.expr[p.off:])
+	return p.error(fmt.Sprintf("unexpected character %q", r))
+}
+
+// error returns a [*SyntaxError] at the current offset.
+func (p *parser) error(reason string) error {
+	return &SyntaxError{Reason: reason, Offset: p.off}
+}
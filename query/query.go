@@ -0,0 +1,313 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package query implements a small JSONPath-like query language over JSONC
+// data, returning the matched values along with their source positions.
+//
+// A query starts with $, the root value, followed by any number of
+// segments selecting the children of the current values:
+//
+//	.name, ['name']     the member with the given name
+//	[0], [-1]           the element with the given index (negative from the end)
+//	['a', 'b'], [0, 1]  the union of the given members or elements
+//	.*, [*]             all the members or the elements
+//	..name, ..*, ..[0]  the selector applied to the value and its descendants
+//	[?(filter)]         the members or the elements matching the filter
+//
+// Filters are built with comparisons (==, !=, <, <=, >, >=), regular
+// expression matches (=~) and the logical operators &&, || and !, grouped
+// by parentheses. The operands are literals (numbers, strings, true, false
+// and null), @key, the name or the index of the filtered member or element,
+// and paths of single values relative to the filtered value (@) or to the
+// root ($), as @.name or $.a[0]. A path alone tests whether the value
+// exists. For example, the following query selects the members of the VS
+// Code settings whose name starts with "editor." set to true:
+//
+//	$[?(@key =~ '^editor\.' && @ == true)]
+//
+// String literals are enclosed in single or double quotes and support the
+// escape sequences of JSON strings, plus \'. Any other backslash is kept as
+// it is, so that regular expressions do not need to be escaped twice.
+//
+// Numbers are compared as float64 values, strings by their bytes, objects
+// and arrays by their decoded values. The ordering operators only compare
+// numbers or strings, and =~ only matches strings.
+package query
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/marcozac/go-jsonc"
+	"github.com/marcozac/go-jsonc/internal/json"
+)
+
+// Query is a compiled query. It is safe for concurrent use.
+type Query struct {
+	expr     string
+	segments []segment
+}
+
+// Compile parses a query and returns, if successful, a [Query] that can be
+// evaluated on JSONC data.
+func Compile(expr string) (*Query, error) {
+	p := parser{expr: expr}
+	segments, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{expr: expr, segments: segments}, nil
+}
+
+// MustCompile is like [Compile] but panics if the query cannot be parsed.
+func MustCompile(expr string) *Query {
+	q, err := Compile(expr)
+	if err != nil {
+		panic(`query: Compile(` + strconv.Quote(expr) + `): ` + err.Error())
+	}
+	return q
+}
+
+// Eval compiles the query and evaluates it on the JSONC data, as done by
+// [Query.Eval].
+func Eval(data []byte, expr string) ([]Match, error) {
+	q, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return q.Eval(data)
+}
+
+// String returns the source text of the query.
+func (q *Query) String() string {
+	return q.expr
+}
+
+// Eval returns the values of the JSONC data matched by the query, in the
+// order they are selected. The values selected more than once, as by
+// recursive descent, are returned once for each selection.
+//
+// It returns the errors reported by [jsonc.Parse].
+func (q *Query) Eval(data []byte) ([]Match, error) {
+	doc, err := jsonc.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	root := item{node: doc.Value}
+	items := []item{root}
+	for _, s := range q.segments {
+		var next []item
+		for _, it := range items {
+			if s.descendant {
+				it.walk(func(d item) { next = s.apply(d, root, next) })
+			} else {
+				next = s.apply(it, root, next)
+			}
+		}
+		items = next
+	}
+	lines := lineStarts(data)
+	matches := make([]Match, len(items))
+	for i, it := range items {
+		r := it.node.Range
+		line := sort.Search(len(lines), func(i int) bool { return lines[i] > r.Start })
+		matches[i] = Match{
+			Path:   it.path,
+			Node:   it.node,
+			Raw:    data[r.Start:r.End],
+			Line:   line,
+			Column: utf8.RuneCount(data[lines[line-1]:r.Start]) + 1,
+		}
+	}
+	return matches, nil
+}
+
+// Match is a value matched by a query.
+type Match struct {
+	// Path is the path of the value, as the one used by [jsonc.Modify].
+	Path []any
+
+	// Node is the value in the concrete syntax tree of the data.
+	Node *jsonc.Node
+
+	// Raw is the source text of the value, including the comments inside
+	// objects and arrays.
+	Raw []byte
+
+	// Line and Column are the line and the column (1-based, in runes) of
+	// the start of the value in the data. The byte range is Node.Range.
+	Line, Column int
+}
+
+// Decode decodes the value into v, as done by [jsonc.Unmarshal].
+func (m *Match) Decode(v any) error {
+	return jsonc.Unmarshal(m.Raw, v)
+}
+
+// Pointer returns the JSON Pointer (RFC 6901) of the value, as the one used
+// by [jsonc.Get].
+func (m *Match) Pointer() string {
+	var b strings.Builder
+	for _, p := range m.Path {
+		b.WriteByte('/')
+		switch p := p.(type) {
+		case string:
+			b.WriteString(strings.ReplaceAll(strings.ReplaceAll(p, "~", "~0"), "/", "~1"))
+		case int:
+			b.WriteString(strconv.Itoa(p))
+		}
+	}
+	return b.String()
+}
+
+// lineStarts returns the offsets of the start of each line of data.
+func lineStarts(data []byte) []int {
+	lines := []int{0}
+	for i := bytes.IndexByte(data, '\n'); i >= 0; {
+		lines = append(lines, i+1)
+		j := bytes.IndexByte(data[i+1:], '\n')
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return lines
+}
+
+// item is a value selected during the evaluation of a query.
+type item struct {
+	node *jsonc.Node
+	path []any
+}
+
+// key returns the name or the index of the value in its parent, or nil for
+// the root value.
+func (it item) key() any {
+	if len(it.path) == 0 {
+		return nil
+	}
+	return it.path[len(it.path)-1]
+}
+
+// child returns the child of the value with the given node and key.
+func (it item) child(n *jsonc.Node, key any) item {
+	return item{node: n, path: append(it.path[:len(it.path):len(it.path)], key)}
+}
+
+// children calls f for each member or element of the value.
+func (it item) children(f func(item)) {
+	for _, m := range it.node.Members {
+		f(it.child(m.Value, m.Name()))
+	}
+	for i, e := range it.node.Elements {
+		f(it.child(e.Value, i))
+	}
+}
+
+// walk calls f for the value and each of its descendants, in document
+// order.
+func (it item) walk(f func(item)) {
+	f(it)
+	it.children(func(c item) { c.walk(f) })
+}
+
+// member returns the last member of the object with the given name.
+func (it item) member(name string) (item, bool) {
+	for i := len(it.node.Members) - 1; i >= 0; i-- {
+		if m := it.node.Members[i]; m.Name() == name {
+			return it.child(m.Value, name), true
+		}
+	}
+	return item{}, false
+}
+
+// element returns the element of the array with the given index, counted
+// from the end if negative.
+func (it item) element(i int) (item, bool) {
+	n := len(it.node.Elements)
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		return item{}, false
+	}
+	return it.child(it.node.Elements[i].Value, i), true
+}
+
+// segment is a step of a query.
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+// apply appends to dst the values selected by the segment from it.
+func (s segment) apply(it, root item, dst []item) []item {
+	for _, sel := range s.selectors {
+		switch {
+		case sel.filter != nil:
+			it.children(func(c item) {
+				if sel.filter.test(c, root) {
+					dst = append(dst, c)
+				}
+			})
+		case sel.wildcard:
+			it.children(func(c item) { dst = append(dst, c) })
+		case sel.name != nil:
+			if c, ok := it.member(*sel.name); ok {
+				dst = append(dst, c)
+			}
+		default:
+			if c, ok := it.element(sel.index); ok {
+				dst = append(dst, c)
+			}
+		}
+	}
+	return dst
+}
+
+// selector selects the children of a value: a member by name, an element by
+// index, all of them (wildcard) or the ones matching a filter.
+type selector struct {
+	name     *string
+	index    int
+	wildcard bool
+	filter   expr
+}
+
+// value returns the Go value of n: float64, string, bool, nil,
+// map[string]any or []any.
+func value(n *jsonc.Node) any {
+	switch n.Kind {
+	case jsonc.KindNumber:
+		f, _ := strconv.ParseFloat(string(n.Raw), 64)
+		return f
+	case jsonc.KindString:
+		if bytes.IndexByte(n.Raw, '\\') < 0 {
+			return string(n.Raw[1 : len(n.Raw)-1])
+		}
+		var s string
+		_ = json.Unmarshal(n.Raw, &s)
+		return s
+	case jsonc.KindBool:
+		return n.Raw[0] == 't'
+	case jsonc.KindNull:
+		return nil
+	}
+	var v any
+	_ = jsonc.Unmarshal(n.Bytes(), &v)
+	return v
+}
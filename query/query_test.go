@@ -0,0 +1,217 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package query
+
+import (
+	"testing"
+
+	"github.com/marcozac/go-jsonc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _settings = []byte(`{
+  // Editor
+  "editor.fontSize": 14,
+  "editor.formatOnSave": true, // format
+  "editor.minimap.enabled": false,
+  "editor.wordWrap": "on",
+  "files.trimTrailingWhitespace": true,
+  /* Tests */
+  "tests": [
+    {"name": "a", "timeout": 10, "tags": ["fast"]},
+    {"name": "b", "timeout": 30, "tags": []},
+    {"name": "c\/d", "timeout": 20, "env": {"name": "nested"}},
+  ],
+  "nested": {"editor.fontSize": 12},
+  "esc'aped": 1,
+  "dup": 1,
+  "dup": 2
+}`)
+
+func TestEval(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name  string
+		Query string
+		Want  []string // pointers of the matches
+	}{
+		{"Root", "$", []string{""}},
+		{"Child", "$.tests[0].name", []string{"/tests/0/name"}},
+		{"BracketChild", "$['editor.fontSize']", []string{"/editor.fontSize"}},
+		{"DoubleQuotes", `$["esc'aped"]`, []string{"/esc'aped"}},
+		{"Escaped", `$['esc\'aped']`, []string{"/esc'aped"}},
+		{"Duplicate", "$.dup", []string{"/dup"}},
+		{"NegativeIndex", "$.tests[-1].name", []string{"/tests/2/name"}},
+		{"Union", "$.tests[0, 2]['name', 'timeout']", []string{"/tests/0/name", "/tests/0/timeout", "/tests/2/name", "/tests/2/timeout"}},
+		{"Wildcard", "$.tests[*].timeout", []string{"/tests/0/timeout", "/tests/1/timeout", "/tests/2/timeout"}},
+		{"DotWildcard", "$.nested.*", []string{"/nested/editor.fontSize"}},
+		{"Descendant", "$..name", []string{"/tests/0/name", "/tests/1/name", "/tests/2/name", "/tests/2/env/name"}},
+		{"DescendantIndex", "$..[0]", []string{"/tests/0", "/tests/0/tags/0"}},
+		{"DescendantWildcard", "$.tests[0]..*", []string{"/tests/0/name", "/tests/0/timeout", "/tests/0/tags", "/tests/0/tags/0"}},
+		{"Missing", "$.none.name", nil},
+		{"IndexOfObject", "$[0]", nil},
+		{"NameOfArray", "$.tests.name", nil},
+		{"FilterKeyRegexp", `$[?(@key =~ '^editor\.' && @ == true)]`, []string{"/editor.formatOnSave"}},
+		{"FilterEquality", "$[?@ == true]", []string{"/editor.formatOnSave", "/files.trimTrailingWhitespace"}},
+		{"FilterNumber", "$.tests[?(@.timeout >= 20)].name", []string{"/tests/1/name", "/tests/2/name"}},
+		{"FilterString", "$.tests[?(@.name < 'b')]", []string{"/tests/0"}},
+		{"FilterEscapedString", `$.tests[?(@.name == 'c/d')]`, []string{"/tests/2"}},
+		{"FilterNotEqual", "$.tests[?(@.name != 'a')]", []string{"/tests/1", "/tests/2"}},
+		{"FilterExists", "$.tests[?(@.env)]", []string{"/tests/2"}},
+		{"FilterNot", "$.tests[?(!@.env)]", []string{"/tests/0", "/tests/1"}},
+		{"FilterOr", "$.tests[?(@.timeout < 15 || @.timeout > 25)].name", []string{"/tests/0/name", "/tests/1/name"}},
+		{"FilterGroup", "$.tests[?(!(@.name == 'a' || @.name == 'b'))]", []string{"/tests/2"}},
+		{"FilterArray", "$.tests[?(@.tags == $.tests[1].tags)]", []string{"/tests/1"}},
+		{"FilterNestedPath", "$.tests[?(@.env['name'] == 'nested')]", []string{"/tests/2"}},
+		{"FilterIndex", "$.tests[?(@key > 0)]", []string{"/tests/1", "/tests/2"}},
+		{"FilterTypeMismatch", "$.tests[?(@.name > 1)]", nil},
+		{"FilterMissingEqual", "$.tests[?(@.none == @.other)]", []string{"/tests/0", "/tests/1", "/tests/2"}},
+		{"FilterNull", "$.tests[?(@.env != null)]", []string{"/tests/0", "/tests/1", "/tests/2"}},
+		{"Spaces", " $ .tests [ 0 ] .name ", []string{"/tests/0/name"}},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			matches, err := Eval(_settings, tt.Query)
+			require.NoError(t, err)
+			var got []string
+			for _, m := range matches {
+				got = append(got, m.Pointer())
+			}
+			assert.Equal(t, tt.Want, got)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+	q := MustCompile("$.tests[?(@.env)]")
+	assert.Equal(t, "$.tests[?(@.env)]", q.String())
+	matches, err := q.Eval(_settings)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	m := matches[0]
+	assert.Equal(t, []any{"tests", 2}, m.Path)
+	assert.Equal(t, `{"name": "c\/d", "timeout": 20, "env": {"name": "nested"}}`, string(m.Raw))
+	assert.Equal(t, string(m.Raw), string(_settings[m.Node.Range.Start:m.Node.Range.End]))
+	assert.Equal(t, [2]int{12, 5}, [2]int{m.Line, m.Column})
+	var v struct{ Name string }
+	require.NoError(t, m.Decode(&v))
+	assert.Equal(t, "c/d", v.Name)
+	t.Run("Comments", func(t *testing.T) {
+		t.Parallel()
+		matches, err := Eval([]byte("// é\n[\n  /* é */ 1, // x\n  2\n]"), "$[*]")
+		require.NoError(t, err)
+		require.Len(t, matches, 2)
+		assert.Equal(t, [2]int{3, 11}, [2]int{matches[0].Line, matches[0].Column})
+		assert.Equal(t, [2]int{4, 3}, [2]int{matches[1].Line, matches[1].Column})
+	})
+	t.Run("Pointer", func(t *testing.T) {
+		t.Parallel()
+		m := Match{Path: []any{"a/b", "c~d", 1}}
+		assert.Equal(t, "/a~1b/c~0d/1", m.Pointer())
+	})
+}
+
+func TestCompileError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Query string
+		Want  SyntaxError
+	}{
+		{"", SyntaxError{"query must start with $", 0}},
+		{"a", SyntaxError{"query must start with $", 0}},
+		{"$a", SyntaxError{`unexpected character 'a'`, 1}},
+		{"$.", SyntaxError{"unexpected end of query", 2}},
+		{"$.[0]", SyntaxError{`unexpected character '['`, 2}},
+		{"$[", SyntaxError{"unexpected end of query", 2}},
+		{"$[0", SyntaxError{"unexpected end of query", 3}},
+		{"$[a]", SyntaxError{`unexpected character 'a'`, 2}},
+		{"$[-]", SyntaxError{"invalid index", 2}},
+		{"$['a", SyntaxError{"unterminated string", 2}},
+		{`$['\u12']`, SyntaxError{"invalid escape sequence", 4}},
+		{"$[?(@.a == )]", SyntaxError{`unexpected character ')'`, 11}},
+		{"$[?(@.a == 1]", SyntaxError{`unexpected character ']'`, 12}},
+		{"$[?(1)]", SyntaxError{"expected comparison operator", 5}},
+		{"$[?(@.a =~ 1)]", SyntaxError{"expected regular expression string", 11}},
+		{"$[?(@.a =~ '(')]", SyntaxError{"error parsing regexp: missing closing ): `(`", 11}},
+		{"$[?(@.a == 1.2.3)]", SyntaxError{"invalid number", 11}},
+		{"$[?(@. == 1)]", SyntaxError{`unexpected character ' '`, 6}},
+		{"$[?(@[x] == 1)]", SyntaxError{"invalid index", 6}},
+		{"$[?(@ == tru)]", SyntaxError{`unexpected character 't'`, 9}},
+	} {
+		tt := tt
+		t.Run(tt.Query, func(t *testing.T) {
+			t.Parallel()
+			_, err := Compile(tt.Query)
+			var serr *SyntaxError
+			require.ErrorAs(t, err, &serr)
+			assert.Equal(t, tt.Want, *serr)
+		})
+	}
+	assert.PanicsWithValue(t, `query: Compile("$["): query: unexpected end of query at offset 2`, func() {
+		MustCompile("$[")
+	})
+	_, err := Eval([]byte(`{"a": }`), "$.a")
+	var serr *jsonc.SyntaxError
+	assert.ErrorAs(t, err, &serr)
+}
+
+func TestStringLiteral(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Literal string
+		Want    string
+	}{
+		{`'a"b'`, `a"b`},
+		{`"a\"b"`, `a"b`},
+		{`'\b\f\n\r\t\/\\'`, "\b\f\n\r\t/\\"},
+		{`'\u00e9'`, "é"},
+		{`'\ud83d\ude00'`, "\U0001F600"},
+		{`'\ud83d'`, "\uFFFD"},
+		{`'\.\\'`, `\.\`},
+	} {
+		tt := tt
+		t.Run(tt.Literal, func(t *testing.T) {
+			t.Parallel()
+			p := parser{expr: tt.Literal}
+			got, err := p.string()
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, got)
+			assert.Equal(t, len(tt.Literal), p.off)
+		})
+	}
+}
+
+func FuzzCompile(f *testing.F) {
+	for _, q := range [...]string{"$..name", `$.tests[?(@.timeout >= 20 && !(@key == 1))].tags[-1]`, `$['a\'b', "c"][0, 1]`} {
+		f.Add(q)
+	}
+	f.Fuzz(func(t *testing.T, expr string) {
+		q, err := Compile(expr)
+		if err != nil {
+			var serr *SyntaxError
+			require.ErrorAs(t, err, &serr)
+			require.LessOrEqual(t, serr.Offset, len(expr))
+			return
+		}
+		_, err = q.Eval(_settings)
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonc
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// NewReader returns an io.Reader that reads the JSONC data from r and removes
+// all comments from it, so that the output can be consumed by any JSON
+// decoder (e.g. json.NewDecoder) or copied elsewhere.
+//
+// The data is processed incrementally in bounded memory, carrying the state
+// of strings and comments across the calls to Read. It returns
+// [ErrInvalidUTF8] as soon as an invalid UTF-8 sequence is found, after all
+// the data preceding it has been read. Malformed comments and strings are
+// reported as [*SyntaxError], as done by [Sanitize].
+//
+// NOTE: as [Sanitize], it does not check whether the data is valid JSON.
+func NewReader(r io.Reader) io.Reader {
+	return newReader(r, false)
+}
+
+// readerBufSize is the size of the buffer used by reader to read from the
+// underlying reader.
+const readerBufSize = 4096
+
+// reader is an io.Reader that removes the comments from the JSONC data read
+// from the underlying reader.
+//
+// If blank is true, the comments are replaced with spaces (new lines are
+// preserved) instead of being removed, so that the offsets of the output
+// match the ones of the input.
+type reader struct {
+	r     io.Reader
+	s     scanner
+	blank bool
+	buf   []byte // read buffer
+	out   []byte // sanitized data not yet returned, subslice of buf
+	tail  []byte // incomplete rune at the end of the last read, subslice of buf
+	err   error
+}
+
+func newReader(r io.Reader, blank bool) *reader {
+	return &reader{
+		r:     r,
+		blank: blank,
+		buf:   make([]byte, readerBufSize),
+	}
+}
+
+// Read implements the io.Reader interface.
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.fill()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fill reads the next chunk of data from the underlying reader and
+// sanitizes it, validating it in the same pass. An incomplete rune at the end
+// of the chunk is kept and prepended to the next one, so that the UTF-8
+// validation does not depend on how the data is split by the underlying
+// reader.
+func (r *reader) fill() {
+	n := copy(r.buf, r.tail)
+	m, err := r.r.Read(r.buf[n:])
+	n += m
+	b := r.buf[:n]
+	end := n
+	if err == nil {
+		end -= incompleteRuneLen(b)
+	}
+	r.tail = b[end:]
+	r.out = r.s.strip(b[:0], b[:end], r.blank)
+	switch {
+	case r.s.err != nil:
+		err = r.s.err
+	case err == io.EOF:
+		if serr := r.s.eof(); serr != nil {
+			err = serr
+		}
+	}
+	r.err = err
+}
+
+// incompleteRuneLen returns the length of the incomplete rune at the end of
+// b, if any.
+func incompleteRuneLen(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if utf8.FullRune(b[i:]) {
+				return 0
+			}
+			return len(b) - i
+		}
+	}
+	return 0
+}
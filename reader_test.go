@@ -0,0 +1,123 @@
+// Copyright 2023 Marco Zaccaro. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !uncommented_test
+// +build !uncommented_test
+
+package jsonc
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var readerTestReaders = [...]struct {
+	Name   string
+	Reader func(r io.Reader) io.Reader
+}{
+	{"Full", func(r io.Reader) io.Reader { return r }},
+	{"OneByte", iotest.OneByteReader},
+	{"Half", iotest.HalfReader},
+	{"DataErr", iotest.DataErrReader},
+}
+
+func TestReader(t *testing.T) {
+	t.Parallel()
+	for _, d := range []struct {
+		Name string
+		Data []byte
+	}{
+		{"Small", _small},
+		{"Medium", _medium},
+		{"MultiByte", []byte(`{"é": "ü // ñ" /* ☃ */, "😀": 1} // 😀`)},
+	} {
+		d := d
+		for _, tt := range readerTestReaders {
+			tt := tt
+			t.Run(d.Name+"/"+tt.Name, func(t *testing.T) {
+				t.Parallel()
+				want, err := Sanitize(d.Data)
+				require.NoError(t, err)
+				got, err := io.ReadAll(NewReader(tt.Reader(bytes.NewReader(d.Data))))
+				require.NoError(t, err)
+				assert.Equal(t, string(want), string(got))
+			})
+		}
+	}
+}
+
+func TestReaderInvalidUTF8(t *testing.T) {
+	t.Parallel()
+	for _, d := range []struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{"Invalid", "{\"a\": \"b\xa5\"}", `{"a": "b`},
+		{"Truncated", "{\"a\": \"b\"} // \xe2\x98", `{"a": "b"} `},
+	} {
+		d := d
+		for _, tt := range readerTestReaders {
+			tt := tt
+			t.Run(d.Name+"/"+tt.Name, func(t *testing.T) {
+				t.Parallel()
+				got, err := io.ReadAll(NewReader(tt.Reader(strings.NewReader(d.Data))))
+				assert.ErrorIs(t, err, ErrInvalidUTF8)
+				assert.Equal(t, d.Want, string(got))
+			})
+		}
+	}
+}
+
+func TestReaderSyntaxError(t *testing.T) {
+	t.Parallel()
+	for _, d := range sanitizeSyntaxErrorTests {
+		d := d
+		for _, tt := range readerTestReaders {
+			tt := tt
+			t.Run(d.Name+"/"+tt.Name, func(t *testing.T) {
+				t.Parallel()
+				_, err := io.ReadAll(NewReader(tt.Reader(strings.NewReader(d.Data))))
+				var serr *SyntaxError
+				require.ErrorAs(t, err, &serr)
+				assert.Equal(t, d.Want, *serr)
+			})
+		}
+	}
+}
+
+func BenchmarkReader(b *testing.B) {
+	b.Run("Small", func(b *testing.B) {
+		benchmarkReader(b, _small)
+	})
+	b.Run("Medium", func(b *testing.B) {
+		benchmarkReader(b, _medium)
+	})
+}
+
+func benchmarkReader(b *testing.B, data []byte) {
+	b.Helper()
+	b.RunParallel(func(p *testing.PB) {
+		for p.Next() {
+			_, err := io.Copy(io.Discard, NewReader(bytes.NewReader(data)))
+			require.NoError(b, err)
+		}
+	})
+}
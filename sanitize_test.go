@@ -18,10 +18,14 @@
 package jsonc
 
 import (
+	"bytes"
+	"io"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"unicode/utf8"
 
 	"github.com/marcozac/go-jsonc/internal/json"
 	"github.com/stretchr/testify/assert"
@@ -83,6 +87,354 @@ func SanitizeError[T DataType](t require.TestingT, data []byte, dt T) {
 	assert.ErrorIs(t, err, ErrInvalidUTF8, "invalid UTF-8 was not detected")
 }
 
+func TestSanitizeKeepOffsets(t *testing.T) {
+	t.Parallel()
+	t.Run("Small", func(t *testing.T) {
+		t.Parallel()
+		sanitizeKeepOffsetsTest(t, _small, Small{})
+	})
+	t.Run("Medium", func(t *testing.T) {
+		t.Parallel()
+		sanitizeKeepOffsetsTest(t, _medium, Medium{})
+	})
+	t.Run("Content", func(t *testing.T) {
+		t.Parallel()
+		s, err := SanitizeKeepOffsets([]byte("{/* é\n*/\"a\": \"//\" // ☃\n}"))
+		require.NoError(t, err)
+		assert.Equal(t, "{     \n  \"a\": \"//\"       \n}", string(s))
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		_, err := SanitizeKeepOffsets(append(_small, _invalidChar...))
+		assert.ErrorIs(t, err, ErrInvalidUTF8)
+		var serr *SyntaxError
+		_, err = SanitizeKeepOffsets([]byte(`{"a": 1 /* c`))
+		assert.ErrorAs(t, err, &serr)
+	})
+}
+
+func sanitizeKeepOffsetsTest[T DataType](t *testing.T, data []byte, dt T) {
+	t.Helper()
+	s, err := SanitizeKeepOffsets(data)
+	require.NoError(t, err)
+	require.Len(t, s, len(data))
+	for i, c := range s {
+		if c != data[i] {
+			require.Equal(t, byte(' '), c, "unexpected byte at offset %d", i)
+		}
+	}
+	j := dt
+	require.NoError(t, json.Unmarshal(s, &j), "sanitized JSON is invalid")
+	FieldsValue(t, j)
+}
+
+func TestAppendSanitize(t *testing.T) {
+	t.Parallel()
+	for _, data := range [...][]byte{_small, _medium, _escapes} {
+		want, err := Sanitize(data)
+		require.NoError(t, err)
+		dst := []byte("prefix")
+		got, err := AppendSanitize(dst, data)
+		require.NoError(t, err)
+		assert.Equal(t, "prefix"+string(want), string(got))
+	}
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		dst := []byte("prefix")
+		got, err := AppendSanitize(dst, append(_small, _invalidChar...))
+		assert.ErrorIs(t, err, ErrInvalidUTF8)
+		assert.Equal(t, dst, got)
+		got, err = AppendSanitize(dst, []byte(`{"a": 1 /* c`))
+		var serr *SyntaxError
+		assert.ErrorAs(t, err, &serr)
+		assert.Equal(t, dst, got)
+	})
+}
+
+// TestAppendSanitizeAllocs cannot run in parallel, as required by
+// testing.AllocsPerRun.
+func TestAppendSanitizeAllocs(t *testing.T) {
+	dst := make([]byte, 0, len(_medium))
+	allocs := testing.AllocsPerRun(10, func() {
+		_, err := AppendSanitize(dst, _medium)
+		require.NoError(t, err)
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestSanitizeInPlace(t *testing.T) {
+	t.Parallel()
+	for _, data := range [...][]byte{_small, _medium, _escapes} {
+		want, err := Sanitize(data)
+		require.NoError(t, err)
+		buf := bytes.Clone(data)
+		got, err := SanitizeInPlace(buf)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+		assert.Equal(t, &buf[0], &got[0], "data must be sanitized in place")
+	}
+	t.Run("Error", func(t *testing.T) {
+		t.Parallel()
+		got, err := SanitizeInPlace([]byte(`{"a": "b`))
+		var serr *SyntaxError
+		assert.ErrorAs(t, err, &serr)
+		assert.Nil(t, got)
+	})
+	t.Run("SameError", func(t *testing.T) {
+		t.Parallel()
+		for _, data := range [...]string{
+			`/*é*/"a"/*`,
+			`"é" /* é`,
+			"{\"a\": 1 */ \xff",
+			"\"\xff\" /* c",
+			`{"a": "b`,
+		} {
+			_, want := Sanitize([]byte(data))
+			require.Error(t, want)
+			_, err := SanitizeInPlace([]byte(data))
+			assert.Equal(t, want, err, "%q", data)
+		}
+	})
+}
+
+// TestSanitizeEscapes checks that the escape sequences in strings are
+// handled as defined by the JSON grammar, comparing the result with the
+// uncommented version of the same data.
+func TestSanitizeEscapes(t *testing.T) {
+	t.Parallel()
+	var want map[string]any
+	require.NoError(t, json.Unmarshal(_escapesUncommented, &want))
+	for _, tt := range [...]struct {
+		Name   string
+		Decode func(data []byte, v any) error
+	}{
+		{"Sanitize", func(data []byte, v any) error {
+			s, err := Sanitize(data)
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(s, v)
+		}},
+		{"Unmarshal", Unmarshal},
+		{"Reader", func(data []byte, v any) error {
+			s, err := io.ReadAll(NewReader(iotest.OneByteReader(bytes.NewReader(data))))
+			if err != nil {
+				return err
+			}
+			return json.Unmarshal(s, v)
+		}},
+		{"Decoder", func(data []byte, v any) error {
+			return NewDecoder(bytes.NewReader(data)).Decode(v)
+		}},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			var got map[string]any
+			require.NoError(t, tt.Decode(_escapes, &got))
+			assert.Equal(t, want, got)
+		})
+	}
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{"EscapedBackslash", `["C:\\" /* c */, "D:\\"]`, `["C:\\" , "D:\\"]`},
+		{"EscapedQuote", `["a\" // b", "c"] // d`, `["a\" // b", "c"] `},
+		{"BackslashRun", `["\\\\\"" /* c */]`, `["\\\\\"" ]`},
+		{"Unicode", `["\u005c" /* c */]`, `["\u005c" ]`},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			s, err := Sanitize([]byte(tt.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(s))
+		})
+	}
+}
+
+func TestSanitizeSyntaxError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range sanitizeSyntaxErrorTests {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Sanitize([]byte(tt.Data))
+			var serr *SyntaxError
+			require.ErrorAs(t, err, &serr)
+			assert.Equal(t, tt.Want, *serr)
+		})
+	}
+}
+
+var sanitizeSyntaxErrorTests = [...]struct {
+	Name string
+	Data string
+	Want SyntaxError
+}{
+	{
+		"UnterminatedComment",
+		"{\n  \"a\": 1 /* comment\n}",
+		SyntaxError{reasonUnterminatedComment, 11, 2, 10},
+	},
+	{
+		"UnterminatedString",
+		"{\n  // comment\n  \"ä\": \"b}",
+		SyntaxError{reasonUnterminatedString, 23, 3, 8},
+	},
+	{
+		"StrayCommentEnd",
+		"{\"a\": 1 */}",
+		SyntaxError{reasonStrayCommentEnd, 8, 1, 9},
+	},
+}
+
+func TestSanitizeStrict(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data []byte
+	}{
+		{"Small", _small},
+		{"Medium", _medium},
+		{"Escapes", _escapes},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			want, err := Sanitize(tt.Data)
+			require.NoError(t, err)
+			got, err := SanitizeStrict(tt.Data)
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+		})
+	}
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want SyntaxError
+	}{
+		{"Slash", `{"a": 1 / 2}`, SyntaxError{reasonStraySlash, 8, 1, 9}},
+		{"SlashEOF", "{\"a\": 1}\n/", SyntaxError{reasonStraySlash, 9, 2, 1}},
+		{"Star", `{"a": 1 * 2}`, SyntaxError{reasonStrayStar, 8, 1, 9}},
+		{"StarEOF", `{"a": 1}*`, SyntaxError{reasonStrayStar, 8, 1, 9}},
+		{"StarAfterComment", `{"a": 1 /* c */*}`, SyntaxError{reasonStrayStar, 15, 1, 16}},
+		{"CommentEnd", `{"a": 1 */}`, SyntaxError{reasonStrayCommentEnd, 8, 1, 9}},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Sanitize([]byte(tt.Data))
+			if tt.Want.Reason != reasonStrayCommentEnd {
+				assert.NoError(t, err, "stray runes must be accepted in non-strict mode")
+			}
+			_, err = SanitizeStrict([]byte(tt.Data))
+			var serr *SyntaxError
+			require.ErrorAs(t, err, &serr)
+			assert.Equal(t, tt.Want, *serr)
+		})
+	}
+}
+
+func TestSanitizeNested(t *testing.T) {
+	t.Parallel()
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Want string
+	}{
+		{"BlockInLine", "{// a /* b\n\"c\": 1}", "{\n\"c\": 1}"},
+		{"SlashInBlock", "{/* a/b */\"c\": 1}", "{\"c\": 1}"},
+		{"LineInBlock", "{/* // a */\"c\": 1}", "{\"c\": 1}"},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			s, err := Sanitize([]byte(tt.Data))
+			require.NoError(t, err)
+			assert.Equal(t, tt.Want, string(s))
+		})
+	}
+}
+
+// FuzzSanitize checks that the scanner produces the same output and errors
+// as the reference implementation below, which processes the data one rune
+// at a time, with any combination of the scanner options.
+func FuzzSanitize(f *testing.F) {
+	for _, data := range [...][]byte{_small, _medium, _escapes, _json5} {
+		f.Add(data, byte(0))
+	}
+	for i, data := range [...]string{
+		`{"a": "b // c", /* d */ "e": [1, 2,], # f` + "\n}",
+		"{'a\\'': 1, // \u2028 'b': 2 /* * / **/}\r'c': 3,}",
+		`[1 / 2, 3 * 4, */ 5]`,
+		"[\"é\xa5\"] // \xe2\x98",
+		`{"a": 1 /* unterminated`,
+	} {
+		f.Add([]byte(data), byte(i))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, flags byte) {
+		newScanner := func() *scanner {
+			return &scanner{
+				strict:         flags&1 != 0,
+				trailingCommas: flags&2 != 0,
+				json5:          flags&4 != 0,
+				hash:           flags&8 != 0,
+			}
+		}
+		blank := flags&16 != 0
+		want, wantErr := sanitizeRunes(data, newScanner(), blank)
+		got, err := stripAll(data, newScanner(), blank)
+		require.Equal(t, wantErr, err)
+		require.Equal(t, string(want), string(got))
+		if err != nil || flags&2 != 0 {
+			return // the readers do not support trailing commas
+		}
+		r := &reader{r: iotest.OneByteReader(bytes.NewReader(data)), s: *newScanner(), blank: blank, buf: make([]byte, 8)}
+		got, err = io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, string(want), string(got))
+	})
+}
+
+// sanitizeRunes is the reference implementation of the scanner, passing
+// every rune of data to scanner.next.
+func sanitizeRunes(data []byte, s *scanner, blank bool) ([]byte, error) {
+	if !utf8.Valid(data) {
+		return nil, ErrInvalidUTF8
+	}
+	var out []byte
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		keep := s.next(r) >= 0
+		if s.err != nil {
+			break
+		}
+		s.pos.advance(r, size)
+		if keep && r == ',' && s.trailingCommas && s.state&_isString == 0 {
+			keep = !s.isTrailingComma(data[i+size:])
+		}
+		for _, c := range data[i : i+size] {
+			switch {
+			case keep:
+				out = append(out, c)
+			case !blank:
+			case c == '\n':
+				out = append(out, '\n')
+			default:
+				out = append(out, ' ')
+			}
+		}
+		i += size
+	}
+	if err := s.eof(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func BenchmarkSanitize(b *testing.B) {
 	b.Run("Small", func(b *testing.B) {
 		b.Run("Commented", func(b *testing.B) {
@@ -108,6 +460,28 @@ func BenchmarkSanitize(b *testing.B) {
 	})
 }
 
+func BenchmarkAppendSanitize(b *testing.B) {
+	b.Run("Small", func(b *testing.B) {
+		benchmarkAppendSanitize(b, _small)
+	})
+	b.Run("Medium", func(b *testing.B) {
+		benchmarkAppendSanitize(b, _medium)
+	})
+}
+
+func benchmarkAppendSanitize(b *testing.B, data []byte) {
+	b.Helper()
+	b.ReportAllocs()
+	b.RunParallel(func(p *testing.PB) {
+		buf := make([]byte, 0, len(data))
+		for p.Next() {
+			var err error
+			buf, err = AppendSanitize(buf[:0], data)
+			require.NoError(b, err)
+		}
+	})
+}
+
 func benchmarkSanitize[T DataType](b *testing.B, data []byte, dt T) {
 	b.Helper()
 	b.RunParallel(func(p *testing.PB) {
@@ -18,6 +18,7 @@
 package jsonc
 
 import (
+	"errors"
 	"reflect"
 	"runtime"
 	"strings"
@@ -80,6 +81,83 @@ func UnmarshalError[T DataType](t require.TestingT, data []byte, dt T) {
 	assert.ErrorIs(t, Unmarshal(append(data, _invalidChar...), &j), ErrInvalidUTF8, "invalid UTF-8 was not detected")
 }
 
+func TestUnmarshalSyntaxError(t *testing.T) {
+	t.Parallel()
+	for _, tt := range sanitizeSyntaxErrorTests {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			var v any
+			var serr *SyntaxError
+			// ensure that the data is sanitized
+			data := []byte(tt.Data + " // comment")
+			require.ErrorAs(t, Unmarshal(data, &v), &serr)
+			assert.Equal(t, tt.Want, *serr)
+		})
+	}
+}
+
+func TestUnmarshalPositionError(t *testing.T) {
+	t.Parallel()
+	type T struct {
+		A int  `json:"a"`
+		B bool `json:"b"`
+	}
+	for _, tt := range [...]struct {
+		Name string
+		Data string
+		Line int
+	}{
+		{"Syntax", "{\n  /* c */ \"a\": 1, // c\n  // c\n  \"b\": tru\n}", 4},
+		{"Type", "{\n  // c\n  \"a\": /* c */ \"x\"\n}", 3},
+		{"Uncommented", "{\n  \"a\": 1,\n  \"b\": 1\n}", 3},
+	} {
+		tt := tt
+		t.Run(tt.Name, func(t *testing.T) {
+			t.Parallel()
+			var v T
+			err := Unmarshal([]byte(tt.Data), &v)
+			var perr *PositionError
+			require.ErrorAs(t, err, &perr)
+			assert.NotNil(t, errors.Unwrap(err))
+			assert.Equal(t, tt.Line, perr.Line)
+			line := strings.Split(tt.Data, "\n")[tt.Line-1]
+			start := int64(strings.Index(tt.Data, line))
+			assert.GreaterOrEqual(t, perr.Offset, start)
+			assert.LessOrEqual(t, perr.Offset, start+int64(len(line)))
+			assert.Equal(t, int(perr.Offset-start)+1, perr.Column)
+		})
+	}
+	t.Run("AmbiguousContext", func(t *testing.T) {
+		t.Parallel()
+		// the data around the error also appears in the valid "X" member:
+		// the error is reported at the position of "Y", or without position
+		pad := strings.Repeat(" ", 80)
+		data := "{\n  \"X\":" + pad + `{"A": "s"}` + pad + ",\n  \"Y\":" + pad + `{"A": "s"}` + pad + "\n}"
+		var v struct {
+			X map[string]any
+			Y struct{ A int }
+		}
+		err := Unmarshal([]byte(data), &v)
+		require.Error(t, err)
+		var perr *PositionError
+		if errors.As(err, &perr) {
+			assert.Equal(t, 3, perr.Line)
+		}
+	})
+}
+
+func TestOffsetMap(t *testing.T) {
+	t.Parallel()
+	var m offsetMap
+	m.remove(2, 3)
+	m.remove(2, 1)
+	m.remove(5, 2)
+	for off, want := range []int64{0, 1, 6, 7, 8, 11, 12} {
+		assert.Equal(t, want, m.original(int64(off)), "offset %d", off)
+	}
+}
+
 func BenchmarkUnmarshal(b *testing.B) {
 	b.Run("Small", func(b *testing.B) {
 		b.Run("Commented", func(b *testing.B) {